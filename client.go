@@ -1,7 +1,10 @@
 package goya
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"time"
 )
 
 type RequestClient struct {
@@ -11,10 +14,18 @@ type RequestClient struct {
 	Client  *http.Client
 	Request *http.Request
 
-	errs []error
+	errs      []error
+	observers []Observer
+	// built is true after BuildRequest, so Do does not build a request which failed again
+	built bool
 }
 
+// NewRequestClient creates the client of one request
+// The Option set by SetDefaultOption is applied before opt, and opt can be nil
 func NewRequestClient(method, url string, opt *Option, client *http.Client) *RequestClient {
+	if def := DefaultOption(); def != nil || opt == nil {
+		opt = concatOption(def, opt)
+	}
 	return &RequestClient{
 		Method:  method,
 		URL:     url,
@@ -35,36 +46,131 @@ func (c *RequestClient) BuildRequest() *http.Request {
 		c.errs = append(c.errs, builder.Errors()...)
 	}
 	c.Request = request
+	c.built = true
+	c.observers = builder.observers
+	for _, o := range c.observers {
+		o.OnBuild(request, builder.Errors())
+	}
 	return c.Request
 }
 
 // BuildClient will build the client according to the Option and return the built http client
 // Your modifications to the return value will be reflected in the client
 func (c *RequestClient) BuildClient() *http.Client {
-	client := &http.Client{}
-	for _, f := range c.Opt.client {
-		f(client)
-	}
-	c.Client = client
+	c.Client = c.Opt.buildClient()
 	return c.Client
 }
 
 func (c *RequestClient) Do() *Response {
-	if c.Request == nil {
+	if c.Request == nil && !c.built {
 		c.BuildRequest()
 	}
 	if c.Client == nil {
 		c.BuildClient()
 	}
-	resp, err := c.Client.Do(c.Request)
-	if err != nil {
-		c.ErrHappen(err)
+	var resp *http.Response
+	// The request is nil if the build failed, and the errors have been recorded
+	if c.Request != nil {
+		resp = c.send()
 	}
 	result := NewResponse(resp)
+	if len(c.observers) > 0 && resp != nil {
+		resp.Body = &observedBody{ReadCloser: resp.Body, observers: c.observers, resp: result, start: time.Now()}
+	}
 	for _, d := range c.Opt.done {
 		d(c.errs, result)
 	}
-	return NewResponse(resp)
+	return result
+}
+
+// send sends c.Request with c.Client and notifies the observers
+func (c *RequestClient) send() *http.Response {
+	client := c.Client
+	if len(c.observers) > 0 {
+		if attempt, cause := attemptOf(c.Request.Context()); attempt > 1 {
+			for _, o := range c.observers {
+				o.OnRetry(c.Request, attempt, cause)
+			}
+		}
+		client = observeClient(c.Client, c.observers)
+	}
+	if wrappers := sendBodyOf(c.Request.Context()); len(wrappers) > 0 {
+		client = sendBodyClient(client, wrappers)
+	}
+	resp, err := client.Do(c.Request)
+	if err != nil {
+		c.ErrHappen(err)
+		for _, o := range c.observers {
+			o.OnError(c.Request, err)
+		}
+	}
+	return resp
+}
+
+// bodyWrapper wraps the body of req each time it is sent
+type bodyWrapper func(body io.ReadCloser, req *http.Request) io.ReadCloser
+
+type sendBodyKey struct{}
+
+// withSendBody adds wrap to the wrappers of the request body, which are applied by send
+// The body is wrapped when it is sent instead of when it is built, so the options replacing the body
+// such as WithRequestCompression can be in any order, and the body sent again by a redirect is wrapped as well
+func withSendBody(wrap bodyWrapper) AfterBuildFunc {
+	return func(req *http.Request) {
+		if req == nil {
+			return
+		}
+		wrappers := append(append([]bodyWrapper{}, sendBodyOf(req.Context())...), wrap)
+		*req = *req.WithContext(context.WithValue(req.Context(), sendBodyKey{}, wrappers))
+	}
+}
+
+func sendBodyOf(ctx context.Context) []bodyWrapper {
+	wrappers, _ := ctx.Value(sendBodyKey{}).([]bodyWrapper)
+	return wrappers
+}
+
+// sendBodyClient returns a copy of client whose transport wraps the request bodies
+func sendBodyClient(client *http.Client, wrappers []bodyWrapper) *http.Client {
+	wrapped := *client
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	wrapped.Transport = &sendBodyTransport{next: next, wrappers: wrappers}
+	return &wrapped
+}
+
+type sendBodyTransport struct {
+	next     http.RoundTripper
+	wrappers []bodyWrapper
+}
+
+func (t *sendBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return t.next.RoundTrip(req)
+	}
+	// A RoundTripper must not change the request, so the body is set to a copy
+	wrapped := *req
+	wrapped.Body = t.wrap(req.Body, req)
+	// GetBody is used by net/http to send the body again on a new connection
+	if req.GetBody != nil {
+		wrapped.GetBody = func() (io.ReadCloser, error) {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			return t.wrap(body, req), nil
+		}
+	}
+	return t.next.RoundTrip(&wrapped)
+}
+
+func (t *sendBodyTransport) wrap(body io.ReadCloser, req *http.Request) io.ReadCloser {
+	for _, wrap := range t.wrappers {
+		body = wrap(body, req)
+	}
+	return body
 }
 
 // Return all errors that occurred during the Do()
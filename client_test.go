@@ -82,3 +82,25 @@ func TestDo(t *testing.T) {
 		}
 	}
 }
+
+func TestDoInvalidRequest(t *testing.T) {
+	afterCalled := false
+	after := func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, func(req *http.Request) { afterCalled = true }, nil, nil
+	}
+	client := NewRequestClient("GET", "://goya.test", NewOption(after), nil)
+	if req := client.BuildRequest(); req != nil {
+		t.Errorf("BuildRequest got %v but want nil", req)
+	}
+	if afterCalled {
+		t.Error("the AfterBuildFunc is called without a request")
+	}
+	resp := client.Do()
+	if resp == nil || resp.RawResponse != nil {
+		t.Errorf("Do got %v but want a Response without RawResponse", resp)
+	}
+	// only the error of building the request is reported, the request is not sent
+	if errs := client.Errors(); len(errs) != 1 {
+		t.Errorf("Errors got %v but want the error of NewRequest", errs)
+	}
+}
@@ -0,0 +1,179 @@
+package goya
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Compression is the format of a compressed request body
+type Compression string
+
+const (
+	// CompressionGzip sends Content-Encoding: gzip
+	CompressionGzip Compression = "gzip"
+	// CompressionDeflate sends a raw DEFLATE stream with Content-Encoding: deflate
+	CompressionDeflate Compression = "deflate"
+	// CompressionZlib sends a zlib wrapped DEFLATE stream with Content-Encoding: deflate, which is what RFC 9110 means by deflate
+	CompressionZlib Compression = "zlib"
+)
+
+// DefaultDecompressionLimit is the max size of a decompressed response body used by WithDecompression if limit <= 0
+const DefaultDecompressionLimit int64 = 64 << 20
+
+// ErrDecompressionLimit is returned while reading a decompressed body larger than the limit of WithDecompression
+var ErrDecompressionLimit = errors.New("goya: decompressed body exceeds the limit")
+
+// WithRequestCompression will compress the request body and set Content-Encoding
+// It is applied to the final body so it can be used before or after the options setting the body
+func WithRequestCompression(compression Compression) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		if compression != CompressionGzip && compression != CompressionDeflate && compression != CompressionZlib {
+			return func(b *RequestBuider) {
+				b.ErrHappen(fmt.Errorf("WithRequestCompression unknown compression %q", compression))
+			}, nil, nil, nil
+		}
+		return nil, func(req *http.Request) {
+			if req == nil || req.Header.Get("Content-Encoding") != "" {
+				return
+			}
+			body, err := requestBody(req)
+			if err != nil || len(body) == 0 {
+				return
+			}
+			// compress only fails if writing to a bytes.Buffer fails, the body is sent as it is then
+			compressed, err := compress(body, compression)
+			if err != nil {
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(compressed))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(compressed)), nil
+			}
+			req.ContentLength = int64(len(compressed))
+			req.Header.Set("Content-Encoding", compression.contentEncoding())
+		}, nil, nil
+	}
+}
+
+// WithDecompression will decode the gzip and deflate response bodies and set Accept-Encoding if it is not set
+// net/http only decodes gzip by itself if Accept-Encoding is not set by the request
+// Reading more than limit bytes after decoding fails with ErrDecompressionLimit, DefaultDecompressionLimit is used if limit <= 0
+func WithDecompression(limit int64) OptionFunc {
+	if limit <= 0 {
+		limit = DefaultDecompressionLimit
+	}
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, func(req *http.Request) {
+				if req == nil || req.Header.Get("Accept-Encoding") != "" {
+					return
+				}
+				req.Header.Set("Accept-Encoding", "gzip, deflate")
+			}, nil, func(errs []error, resp *Response) {
+				raw := resp.RawResponse
+				if raw == nil || raw.Body == nil {
+					return
+				}
+				encoding := strings.ToLower(strings.TrimSpace(raw.Header.Get("Content-Encoding")))
+				if encoding != "gzip" && encoding != "x-gzip" && encoding != "deflate" {
+					return
+				}
+				raw.Body = &decompressedBody{compressed: raw.Body, encoding: encoding, limit: limit}
+				raw.Header.Del("Content-Encoding")
+				raw.Header.Del("Content-Length")
+				raw.ContentLength = -1
+				raw.Uncompressed = true
+			}
+	}
+}
+
+func (c Compression) contentEncoding() string {
+	if c == CompressionZlib {
+		return "deflate"
+	}
+	return string(c)
+}
+
+func compress(body []byte, compression Compression) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(buf)
+	case CompressionDeflate:
+		fw, err := flate.NewWriter(buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		w = fw
+	case CompressionZlib:
+		w = zlib.NewWriter(buf)
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressedBody creates the decoder on the first Read, because the gzip reader reads the header at once
+type decompressedBody struct {
+	compressed io.ReadCloser
+	encoding   string
+	limit      int64
+	read       int64
+	decoder    io.Reader
+	err        error
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.decoder == nil {
+		b.decoder, b.err = newDecoder(b.compressed, b.encoding)
+		if b.err != nil {
+			return 0, b.err
+		}
+	}
+	// Read one byte more than the limit to know whether the body exceeds it
+	if remain := b.limit - b.read + 1; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	n, err := b.decoder.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		b.err = ErrDecompressionLimit
+		return n - int(b.read-b.limit), b.err
+	}
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	if c, ok := b.decoder.(io.Closer); ok {
+		c.Close()
+	}
+	return b.compressed.Close()
+}
+
+// newDecoder accepts both zlib wrapped and raw DEFLATE streams for deflate, as the browsers do
+func newDecoder(r io.Reader, encoding string) (io.Reader, error) {
+	if encoding != "deflate" {
+		return gzip.NewReader(r)
+	}
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
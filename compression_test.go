@@ -0,0 +1,104 @@
+package goya
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestCompression(t *testing.T) {
+	ts := []struct {
+		compression Compression
+		encoding    string
+		decode      func(r io.Reader) (io.Reader, error)
+	}{
+		{CompressionGzip, "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{CompressionDeflate, "deflate", func(r io.Reader) (io.Reader, error) { return flate.NewReader(r), nil }},
+		{CompressionZlib, "deflate", func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+	}
+	for _, tt := range ts {
+		var encoding, body string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding = r.Header.Get("Content-Encoding")
+			decoder, err := tt.decode(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			bts, _ := io.ReadAll(decoder)
+			body = string(bts)
+		})
+		RequestRaw(http.MethodPost, "http://goya.test/post", NewOption(
+			WithRequestCompression(tt.compression),
+			WithHandler(handler),
+			WithJson(map[string]string{"name": "goya"}),
+		))
+		if encoding != tt.encoding {
+			t.Errorf("%v Content-Encoding got %v but want %v", tt.compression, encoding, tt.encoding)
+		}
+		if body != `{"name":"goya"}` {
+			t.Errorf("%v body got %v but want %v", tt.compression, body, `{"name":"goya"}`)
+		}
+	}
+
+	errs := []error{}
+	RequestRaw(http.MethodPost, "http://goya.test/post", NewOption(WithRequestCompression("br"), WithHandler(http.NotFoundHandler()), WithError(&errs)))
+	if want := `WithRequestCompression unknown compression "br"`; len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("unknown compression got %v but want %v", errs, want)
+	}
+}
+
+func TestWithDecompression(t *testing.T) {
+	content := strings.Repeat("goya", 100)
+	ts := []struct {
+		encoding string
+		compress Compression
+	}{
+		{"gzip", CompressionGzip},
+		{"deflate", CompressionDeflate},
+		{"deflate", CompressionZlib},
+	}
+	for _, tt := range ts {
+		compressed, _ := compress([]byte(content), tt.compress)
+		var acceptEncoding string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			acceptEncoding = r.Header.Get("Accept-Encoding")
+			w.Header().Set("Content-Encoding", tt.encoding)
+			w.Write(compressed)
+		})
+		resp := RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithHandler(handler), WithDecompression(0)))
+		got, err := resp.String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != content {
+			t.Errorf("%v body got %v but want %v", tt.compress, got, content)
+		}
+		if acceptEncoding != "gzip, deflate" || resp.Header.Get("Content-Encoding") != "" {
+			t.Errorf("%v Accept-Encoding got %v, Content-Encoding got %v", tt.compress, acceptEncoding, resp.Header.Get("Content-Encoding"))
+		}
+	}
+
+	bomb := &bytes.Buffer{}
+	w := gzip.NewWriter(bomb)
+	w.Write(make([]byte, 1<<20))
+	w.Close()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(bomb.Bytes())
+	})
+	resp := RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithHandler(handler), WithDecompression(1024)))
+	bts, err := resp.Bytes()
+	if !errors.Is(err, ErrDecompressionLimit) {
+		t.Errorf("err got %v but want %v", err, ErrDecompressionLimit)
+	}
+	if len(bts) != 1024 {
+		t.Errorf("len(bts) got %v but want %v", len(bts), 1024)
+	}
+}
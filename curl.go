@@ -0,0 +1,211 @@
+package goya
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// CurlOption changes how a request is rendered as a curl command
+type CurlOption func(c *curlConfig)
+
+type curlConfig struct {
+	headers []string
+	params  []string
+}
+
+// CurlRedacted replaces the default credentials in the headers and the query params by REDACTED
+// The defaults are the same as WithLogger
+func CurlRedacted() CurlOption {
+	return func(c *curlConfig) {
+		c.headers = append(c.headers, defaultRedactedHeaders...)
+		c.params = append(c.params, defaultRedactedParams...)
+	}
+}
+
+// CurlRedactHeaders replaces the values of the headers by REDACTED
+func CurlRedactHeaders(headers ...string) CurlOption {
+	return func(c *curlConfig) {
+		c.headers = append(c.headers, headers...)
+	}
+}
+
+// CurlRedactQuery replaces the values of the query params by REDACTED
+func CurlRedactQuery(params ...string) CurlOption {
+	return func(c *curlConfig) {
+		c.params = append(c.params, params...)
+	}
+}
+
+// Curl renders the request as a curl command that can be pasted into a shell
+// The request is built first if it has not been built yet
+func (c *RequestClient) Curl(opts ...CurlOption) (string, error) {
+	if c.Request == nil {
+		c.BuildRequest()
+	}
+	if c.Request == nil {
+		return "", fmt.Errorf("the request can not be built : %v", c.Errors())
+	}
+	return curlCommand(c.Request, opts...)
+}
+
+// WithCurlDump will write the curl command of each sent request to w, one per line
+// The redirected requests are written as well
+func WithCurlDump(w io.Writer, opts ...CurlOption) OptionFunc {
+	return WithObserver(&curlObserver{w: w, opts: opts})
+}
+
+type curlObserver struct {
+	NopObserver
+	w    io.Writer
+	opts []CurlOption
+}
+
+func (o *curlObserver) OnSend(req *http.Request) {
+	cmd, err := curlCommand(req, o.opts...)
+	if err != nil {
+		fmt.Fprintf(o.w, "# %v\n", err)
+		return
+	}
+	fmt.Fprintln(o.w, cmd)
+}
+
+func curlCommand(req *http.Request, opts ...CurlOption) (string, error) {
+	config := curlConfig{}
+	for _, f := range opts {
+		f(&config)
+	}
+	header := redactHeader(req.Header, config.headers)
+	if req.Host != "" && req.Host != req.URL.Host {
+		header.Set("Host", req.Host)
+	}
+
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"curl"}
+	bodyArgs := []string{}
+	mediaType, params, _ := mime.ParseMediaType(header.Get(contentType))
+	if strings.HasPrefix(mediaType, "multipart/form-data") && len(body) > 0 {
+		fields, err := multipartArgs(body, params["boundary"])
+		if err != nil {
+			return "", err
+		}
+		bodyArgs = fields
+		// curl generates its own boundary
+		header.Del(contentType)
+	} else if len(body) > 0 {
+		bodyArgs = []string{"--data-binary", shellQuote(string(body))}
+	}
+
+	// A body makes curl send POST, so -X is only needed for the other methods
+	// curl -X HEAD waits for a body that never comes, -I sends HEAD and reads only the headers
+	if req.Method == http.MethodHead && len(bodyArgs) == 0 {
+		args = append(args, "-I")
+	} else if (len(bodyArgs) == 0 && req.Method != http.MethodGet) || (len(bodyArgs) > 0 && req.Method != http.MethodPost) {
+		args = append(args, "-X", req.Method)
+	}
+	URL := redactURL(req.URL, config.params)
+	// curl treats brackets and braces as URL globbing patterns
+	if strings.ContainsAny(URL, "[]{}") {
+		args = append(args, "-g")
+	}
+	args = append(args, shellQuote(URL))
+
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			args = append(args, "-H", shellQuote(k+": "+v))
+		}
+	}
+	args = append(args, bodyArgs...)
+	return strings.Join(args, " "), nil
+}
+
+// requestBody reads the body of req without consuming it
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	bts, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(bts))
+	return bts, nil
+}
+
+// multipartArgs renders the parts of a multipart body as -F arguments
+func multipartArgs(body []byte, boundary string) ([]string, error) {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	args := []string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return args, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() != "" {
+			args = append(args, "-F", shellQuote(fmt.Sprintf("%s=@%s", part.FormName(), part.FileName())))
+			continue
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--form-string", shellQuote(part.FormName()+"="+string(value)))
+	}
+}
+
+// shellQuote quotes s for POSIX shells, using $'...' if s has characters that are not printable
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	printable := utf8.ValidString(s) && strings.IndexFunc(s, func(r rune) bool {
+		return r < 0x20 && r != '\n' && r != '\t' || r == 0x7f
+	}) < 0
+	if printable {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	builder := strings.Builder{}
+	builder.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b == '\'' || b == '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(b)
+		case b >= 0x20 && b < 0x7f:
+			builder.WriteByte(b)
+		default:
+			fmt.Fprintf(&builder, "\\x%02x", b)
+		}
+	}
+	builder.WriteString("'")
+	return builder.String()
+}
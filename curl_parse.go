@@ -0,0 +1,476 @@
+package goya
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// curlIgnoredFlags do not change the request, the value is true if the flag takes an argument
+// -L is the default behavior of http.Client
+var curlIgnoredFlags = map[string]bool{
+	"-s": false, "--silent": false, "-S": false, "--show-error": false, "-v": false, "--verbose": false,
+	"-i": false, "--include": false, "-L": false, "--location": false, "-f": false, "--fail": false,
+	"-N": false, "--no-buffer": false, "--http1.1": false, "--http2": false,
+	"-g": false, "--globoff": false,
+	"-o": true, "--output": true, "--retry": true, "-w": true, "--write-out": true,
+}
+
+// curlShortFlagsWithArg are the short flags that take an argument, such as -XPOST or -X POST
+const curlShortFlagsWithArg = "XHdFubAemow"
+
+// ParseCurl converts a curl command into the method, the URL and the Option of goya
+//
+// The supported flags are -X, -H, -d, --data-raw, --data-binary, --data-urlencode, -F, --form-string,
+// -u, -b, -A, -e, -I, -G, -k, -m/--max-time, --connect-timeout, --compressed and --url,
+// the flags only changing the output of curl are ignored
+func ParseCurl(cmd string) (method, URL string, opt *Option, err error) {
+	args, err := splitShell(cmd)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if len(args) > 0 && (args[0] == "curl" || filepath.Base(args[0]) == "curl") {
+		args = args[1:]
+	}
+
+	p := &curlParser{header: http.Header{}, form: map[string][]string{}, files: map[string][]curlFile{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			p.URL = arg
+			continue
+		}
+		name, value, hasValue := arg, "", false
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			// -XPOST, or combined flags such as -sSL
+			idx := strings.IndexAny(arg[1:], curlShortFlagsWithArg)
+			switch {
+			case idx == 0:
+				name, value, hasValue = arg[:2], arg[2:], true
+			case idx > 0:
+				for _, c := range arg[1 : idx+1] {
+					if err := p.flag("-"+string(c), ""); err != nil {
+						return "", "", nil, err
+					}
+				}
+				name = "-" + string(arg[idx+1])
+				if idx+2 < len(arg) {
+					value, hasValue = arg[idx+2:], true
+				}
+			default:
+				for _, c := range arg[1:] {
+					if err := p.flag("-"+string(c), ""); err != nil {
+						return "", "", nil, err
+					}
+				}
+				continue
+			}
+		}
+		if !hasValue && curlTakesArg(name) {
+			if i+1 >= len(args) {
+				return "", "", nil, fmt.Errorf("curl flag %v needs an argument", name)
+			}
+			i++
+			value = args[i]
+		}
+		if err := p.flag(name, value); err != nil {
+			return "", "", nil, err
+		}
+	}
+	return p.result()
+}
+
+type curlFile struct {
+	path string
+	// content means the file is sent as the value of the field, like -F name=<file
+	content bool
+}
+
+type curlParser struct {
+	method  string
+	URL     string
+	header  http.Header
+	data    []string
+	form    map[string][]string
+	files   map[string][]curlFile
+	cookies []*http.Cookie
+	get     bool
+	head    bool
+	timeout time.Duration
+	// connectTimeout is set by --connect-timeout
+	connectTimeout time.Duration
+	// compressed is set by --compressed
+	compressed bool
+	// insecure is set by -k
+	insecure bool
+}
+
+func curlTakesArg(name string) bool {
+	if withArg, ok := curlIgnoredFlags[name]; ok {
+		return withArg
+	}
+	if len(name) == 2 {
+		return strings.Contains(curlShortFlagsWithArg, name[1:])
+	}
+	switch name {
+	case "--request", "--header", "--data", "--data-ascii", "--data-raw", "--data-binary", "--data-urlencode",
+		"--form", "--form-string", "--user", "--cookie", "--user-agent", "--referer", "--max-time", "--connect-timeout", "--url":
+		return true
+	}
+	return false
+}
+
+func (p *curlParser) flag(name, value string) error {
+	switch name {
+	case "-X", "--request":
+		p.method = strings.ToUpper(value)
+	case "-H", "--header":
+		k, v, ok := strings.Cut(value, ":")
+		if !ok {
+			return fmt.Errorf("curl header %q is invalid", value)
+		}
+		p.header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	case "-d", "--data", "--data-ascii":
+		data, err := curlReadData(value, true)
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, data)
+	case "--data-binary":
+		data, err := curlReadData(value, false)
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, data)
+	case "--data-raw":
+		p.data = append(p.data, value)
+	case "--data-urlencode":
+		data, err := curlURLEncode(value)
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, data)
+	case "-F", "--form":
+		k, v, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("curl form %q is invalid", value)
+		}
+		if strings.HasPrefix(v, "@") || strings.HasPrefix(v, "<") {
+			// ;type= and ;filename= are not supported
+			path, _, _ := strings.Cut(v[1:], ";")
+			p.files[k] = append(p.files[k], curlFile{path: path, content: v[0] == '<'})
+			return nil
+		}
+		p.form[k] = append(p.form[k], v)
+	case "--form-string":
+		k, v, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("curl form %q is invalid", value)
+		}
+		p.form[k] = append(p.form[k], v)
+	case "-u", "--user":
+		p.header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+	case "-b", "--cookie":
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("curl cookie file %q is not supported", value)
+		}
+		for _, pair := range strings.Split(value, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || k == "" {
+				return fmt.Errorf("curl cookie %q is invalid", value)
+			}
+			p.cookies = append(p.cookies, &http.Cookie{Name: k, Value: v})
+		}
+	case "-A", "--user-agent":
+		p.header.Set("User-Agent", value)
+	case "-e", "--referer":
+		p.header.Set("Referer", value)
+	case "-I", "--head":
+		p.head = true
+	case "-G", "--get":
+		p.get = true
+	case "-k", "--insecure":
+		p.insecure = true
+	case "-m", "--max-time":
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("curl max time %q is invalid : %w", value, err)
+		}
+		p.timeout = time.Duration(seconds * float64(time.Second))
+	case "--connect-timeout":
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("curl connect timeout %q is invalid : %w", value, err)
+		}
+		p.connectTimeout = time.Duration(seconds * float64(time.Second))
+	case "--compressed":
+		p.compressed = true
+	case "--url":
+		p.URL = value
+	default:
+		if _, ok := curlIgnoredFlags[name]; !ok {
+			return fmt.Errorf("curl flag %v is not supported", name)
+		}
+	}
+	return nil
+}
+
+func (p *curlParser) result() (string, string, *Option, error) {
+	if p.URL == "" {
+		return "", "", nil, fmt.Errorf("curl command has no URL")
+	}
+	URL := p.URL
+	if !strings.Contains(URL, "://") {
+		URL = "http://" + URL
+	}
+	if _, err := url.Parse(URL); err != nil {
+		return "", "", nil, fmt.Errorf("curl URL is invalid : %w", err)
+	}
+
+	opts := []OptionFunc{}
+	method := http.MethodGet
+	data := strings.Join(p.data, "&")
+	switch {
+	case p.get && len(p.data) > 0:
+		sep := "?"
+		if strings.Contains(URL, "?") {
+			sep = "&"
+		}
+		URL += sep + data
+	case len(p.files) > 0:
+		method = http.MethodPost
+		body, contentTypeValue, err := p.multipart()
+		if err != nil {
+			return "", "", nil, err
+		}
+		opts = append(opts, withRawBody(body, contentTypeValue))
+	case len(p.form) > 0:
+		method = http.MethodPost
+		opts = append(opts, WithForm(p.form))
+	case len(p.data) > 0:
+		method = http.MethodPost
+		opts = append(opts, withRawBody([]byte(data), "application/x-www-form-urlencoded"))
+	}
+	if p.head {
+		method = http.MethodHead
+	}
+	if p.method != "" {
+		method = p.method
+	}
+
+	if len(p.cookies) > 0 {
+		opts = append(opts, WithCookies(p.cookies))
+	}
+	if p.timeout > 0 {
+		opts = append(opts, WithTimeout(p.timeout))
+	}
+	if p.connectTimeout > 0 {
+		opts = append(opts, WithDialTimeout(p.connectTimeout))
+	}
+	if p.compressed {
+		opts = append(opts, WithDecompression(0))
+	}
+	if p.insecure {
+		opts = append(opts, WithInsecureSkipVerify())
+	}
+	// The headers are the last so that they override the Content-Type set by the body
+	if len(p.header) > 0 {
+		opts = append(opts, WithForceHeaders(p.header))
+	}
+	return method, URL, NewOption(opts...), nil
+}
+
+// multipart builds the body of -F with files
+func (p *curlParser) multipart() ([]byte, string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for k, values := range p.form {
+		for _, v := range values {
+			if err := writer.WriteField(k, v); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for k, files := range p.files {
+		for _, f := range files {
+			content, err := os.ReadFile(f.path)
+			if err != nil {
+				return nil, "", err
+			}
+			if f.content {
+				err = writer.WriteField(k, string(content))
+			} else {
+				var part io.Writer
+				part, err = writer.CreateFormFile(k, filepath.Base(f.path))
+				if err == nil {
+					_, err = part.Write(content)
+				}
+			}
+			if err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+// curlReadData reads @file like curl, -d removes the newlines of the file but --data-binary does not
+func curlReadData(value string, stripNewlines bool) (string, error) {
+	if !strings.HasPrefix(value, "@") {
+		return value, nil
+	}
+	if value == "@-" {
+		return "", fmt.Errorf("curl data from stdin is not supported")
+	}
+	content, err := os.ReadFile(value[1:])
+	if err != nil {
+		return "", err
+	}
+	if stripNewlines {
+		return strings.NewReplacer("\r", "", "\n", "").Replace(string(content)), nil
+	}
+	return string(content), nil
+}
+
+// curlURLEncode implements the formats of --data-urlencode : content, =content, name=content, @file and name@file
+func curlURLEncode(value string) (string, error) {
+	if i := strings.IndexAny(value, "=@"); i >= 0 {
+		name, content := value[:i], value[i+1:]
+		if value[i] == '@' {
+			bts, err := os.ReadFile(content)
+			if err != nil {
+				return "", err
+			}
+			content = string(bts)
+		}
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	}
+	return url.QueryEscape(value), nil
+}
+
+// withRawBody sets the body and the Content-Type of the request as they are
+func withRawBody(body []byte, contentTypeValue string) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return func(b *RequestBuider) {
+				b.Body = body
+			}, func(req *http.Request) {
+				req.Header.Set(contentType, contentTypeValue)
+			}, nil, nil
+	}
+}
+
+// splitShell splits cmd into words like a POSIX shell, supporting '...', "...", $'...', backslashes and line continuations
+func splitShell(cmd string) ([]string, error) {
+	args := []string{}
+	current := strings.Builder{}
+	inWord := false
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 < len(cmd) {
+				i++
+				if cmd[i] != '\n' {
+					current.WriteByte(cmd[i])
+					inWord = true
+				}
+			}
+		case c == '\'':
+			end := strings.IndexByte(cmd[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			current.WriteString(cmd[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(cmd) && cmd[i+1] == '\'':
+			n, err := readANSIQuote(cmd[i+2:], &current)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(cmd) && cmd[i] != '"'; i++ {
+				if cmd[i] == '\\' && i+1 < len(cmd) && strings.IndexByte("\"\\$`\n", cmd[i+1]) >= 0 {
+					i++
+					if cmd[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteByte(cmd[i])
+			}
+			if i >= len(cmd) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		default:
+			current.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// readANSIQuote reads the content of $'...' until the closing quote and returns the number of bytes read including it
+func readANSIQuote(s string, out *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '\'': '\'', '"': '"', 'a': '\a', 'b': '\b', 'e': 0x1b, 'f': '\f', 'v': '\v'}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			return i + 1, nil
+		case '\\':
+			if i+1 >= len(s) {
+				return 0, fmt.Errorf("unterminated ANSI-C quote")
+			}
+			i++
+			if b, ok := escapes[s[i]]; ok {
+				out.WriteByte(b)
+				continue
+			}
+			if s[i] == 'x' {
+				end := i + 1
+				for end < len(s) && end < i+3 && strings.IndexByte("0123456789abcdefABCDEF", s[end]) >= 0 {
+					end++
+				}
+				v, err := strconv.ParseUint(s[i+1:end], 16, 8)
+				if err != nil {
+					return 0, fmt.Errorf("invalid escape in ANSI-C quote : %w", err)
+				}
+				out.WriteByte(byte(v))
+				i = end - 1
+				continue
+			}
+			out.WriteByte('\\')
+			out.WriteByte(s[i])
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	return 0, fmt.Errorf("unterminated ANSI-C quote")
+}
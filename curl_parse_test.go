@@ -0,0 +1,101 @@
+package goya
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sagayosa/goya/goyatest"
+)
+
+func TestParseCurl(t *testing.T) {
+	handler := WithHandler(goyatest.NewHTTPBinHandler())
+	send := func(cmd string) (string, BasicPostResponse) {
+		method, URL, opt, err := ParseCurl(cmd)
+		if err != nil {
+			t.Fatalf("ParseCurl(%q) got error %v", cmd, err)
+		}
+		return method, Request[BasicPostResponse](method, URL, concatOption(opt, NewOption(handler)))
+	}
+
+	method, resp := send(`curl -sSL -XPUT 'http://goya.test/put?a=1' -H 'Content-Type: application/json' --data-raw '{"name":"it'\''s goya"}'`)
+	if method != http.MethodPut || resp.Data != `{"name":"it's goya"}` || resp.Headers.ContentType != "application/json" {
+		t.Errorf("PUT got %v %v %v", method, resp.Data, resp.Headers.ContentType)
+	}
+
+	method, resp = send("curl goya.test/post \\\n  -d a=1 --data-urlencode 'b=x y' -u user:passwd -b 'Test=123; Test2=321' -A goya")
+	form, _ := resp.Form.(map[string]any)
+	if method != http.MethodPost || form["a"] != "1" || form["b"] != "x y" {
+		t.Errorf("POST got %v %v", method, resp.Form)
+	}
+	if resp.Headers.Cookie != "Test=123; Test2=321" || resp.Headers.UserAgent != "goya" {
+		t.Errorf("POST headers got %+v", resp.Headers)
+	}
+
+	method, resp = send(`curl -G http://goya.test/get -d a=1 --data-urlencode "b=x&y"`)
+	if method != http.MethodGet || !reflect.DeepEqual(resp.Args, map[string]any{"a": "1", "b": "x&y"}) {
+		t.Errorf("GET got %v %v", method, resp.Args)
+	}
+
+	file := filepath.Join(t.TempDir(), "goya.txt")
+	os.WriteFile(file, []byte("file content"), 0o644)
+	_, resp = send(`curl http://goya.test/post -F name=goya -F "upload=@` + file + `"`)
+	if !reflect.DeepEqual(resp.Form, map[string]any{"name": "goya"}) || !reflect.DeepEqual(resp.Files, map[string]any{"upload": "file content"}) {
+		t.Errorf("multipart got %v %v", resp.Form, resp.Files)
+	}
+}
+
+func TestParseCurlOption(t *testing.T) {
+	method, URL, opt, err := ParseCurl(`curl --max-time 1.5 -k -I $'https://goya.test/\x61'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodHead || URL != "https://goya.test/a" {
+		t.Errorf("got %v %v", method, URL)
+	}
+	client := NewRequestClient(method, URL, opt, nil).BuildClient()
+	if client.Timeout != 1500*time.Millisecond {
+		t.Errorf("Timeout got %v but want %v", client.Timeout, 1500*time.Millisecond)
+	}
+	if transport, ok := client.Transport.(*http.Transport); !ok || !transport.TLSClientConfig.InsecureSkipVerify {
+		t.Error("-k should skip the verification")
+	}
+
+	_, _, opt, err = ParseCurl(`curl --compressed --connect-timeout 0.5 http://goya.test/`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := NewRequestClient(http.MethodGet, "http://goya.test/", opt, nil)
+	if got := rc.BuildRequest().Header.Get("Accept-Encoding"); got != "gzip, deflate" {
+		t.Errorf("Accept-Encoding with --compressed got %v but want %v", got, "gzip, deflate")
+	}
+	if transport, ok := rc.BuildClient().Transport.(*http.Transport); !ok || transport.DialContext == nil {
+		t.Error("--connect-timeout should set the dialer")
+	}
+
+	for _, cmd := range []string{`curl`, `curl 'http://a`, `curl --unknown http://a`, `curl -H http://a`, `curl -b cookies.txt http://a`, `curl --connect-timeout x http://a`} {
+		if _, _, _, err := ParseCurl(cmd); err == nil {
+			t.Errorf("ParseCurl(%q) should fail", cmd)
+		}
+	}
+}
+
+func TestCurlRoundTrip(t *testing.T) {
+	opt := NewOption(WithParams(map[string]string{"q": "a b"}), WithJson(map[string]any{"name": "goya\n'quoted'"}), WithForceHeader("Test-Header", "1"))
+	cmd, err := NewRequestClient(http.MethodPatch, "http://goya.test/anything", opt, nil).Curl()
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, URL, parsed, err := ParseCurl(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := NewRequestClient(http.MethodPatch, "http://goya.test/anything", opt, nil).Curl()
+	got, _ := NewRequestClient(method, URL, parsed, nil).Curl()
+	if got != want {
+		t.Errorf("round trip got %v but want %v", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package goya
+
+import (
+	"bytes"
+	"net/http"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sagayosa/goya/goyatest"
+)
+
+func TestCurl(t *testing.T) {
+	ts := []struct {
+		method string
+		opt    *Option
+		want   string
+	}{
+		{
+			http.MethodGet,
+			NewOption(WithParams(map[string]string{"q": "it's"}), WithCookies([]*http.Cookie{{Name: "Test", Value: "123"}})),
+			`curl 'http://goya.test/get?q=it%27s' -H 'Cookie: Test=123'`,
+		},
+		{
+			http.MethodPost,
+			NewOption(WithJson(map[string]string{"name": "it's goya"}), WithForceHeader("Authorization", "Bearer secret")),
+			`curl http://goya.test/get -H 'Authorization: Bearer secret' -H 'Content-Type: application/json' --data-binary '{"name":"it'\''s goya"}'`,
+		},
+		{
+			http.MethodPut,
+			NewOption(WithForm(map[string]string{"name": "goya"})),
+			`curl -X PUT http://goya.test/get --form-string name=goya`,
+		},
+		{
+			http.MethodDelete,
+			NewOption(),
+			`curl -X DELETE http://goya.test/get`,
+		},
+		{
+			http.MethodHead,
+			NewOption(),
+			`curl -I http://goya.test/get`,
+		},
+	}
+	for _, tt := range ts {
+		got, err := NewRequestClient(tt.method, "http://goya.test/get", tt.opt, nil).Curl()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Curl got %v but want %v", got, tt.want)
+		}
+	}
+
+	redacted, _ := NewRequestClient(http.MethodGet, "http://goya.test/get", NewOption(
+		WithParams(map[string]string{"token": "secret", "sig": "secret"}),
+		WithForceHeader("Authorization", "Bearer secret"),
+	), nil).Curl(CurlRedacted(), CurlRedactQuery("sig"))
+	if strings.Contains(redacted, "secret") {
+		t.Errorf("Curl got %v with secrets", redacted)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not found")
+	}
+	args := []string{"plain", "", "it's", "a b\nc", "$HOME `x` \\", "\x01\x7f'\\", "中文"}
+	quoted := []string{}
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	// bash understands $'...'
+	if bash, err := exec.LookPath("bash"); err == nil {
+		sh = bash
+	} else {
+		args, quoted = args[:5], quoted[:5]
+	}
+	out, err := exec.Command(sh, "-c", `for a in `+strings.Join(quoted, " ")+`; do printf '%s\0' "$a"; done`).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if !reflect.DeepEqual(got, args) {
+		t.Errorf("shell got %q but want %q", got, args)
+	}
+}
+
+func TestWithCurlDump(t *testing.T) {
+	buf := &bytes.Buffer{}
+	RequestRaw(http.MethodGet, "http://goya.test/redirect/1", NewOption(WithHandler(goyatest.NewHTTPBinHandler()), WithCurlDump(buf)))
+	want := "curl http://goya.test/redirect/1\ncurl http://goya.test/get -H 'Referer: http://goya.test/redirect/1'\n"
+	if buf.String() != want {
+		t.Errorf("dump got %q but want %q", buf.String(), want)
+	}
+}
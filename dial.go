@@ -0,0 +1,107 @@
+package goya
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithDialer will open the connections of the transport with dial, addr is the host:port of the request or of the proxy
+func WithDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		if dial == nil {
+			return func(b *RequestBuider) { b.ErrHappen(fmt.Errorf("WithDialer dial is nil")) }, nil, nil, nil
+		}
+		return nil, nil, func(client *http.Client) {
+			configureDial(client, func(c *dialConfig) {
+				c.dial = dial
+			})
+		}, nil
+	}
+}
+
+// WithUnixSocket will send the requests to the Unix domain socket at path whatever the host of the URL is,
+// such as http://unix/containers/json for the Docker daemon. The proxies are not used
+func WithUnixSocket(path string) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			configureTransport(client, func(t *http.Transport) {
+				t.Proxy = nil
+			})
+			configureDial(client, func(c *dialConfig) {
+				c.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+					dialer := net.Dialer{}
+					return dialer.DialContext(ctx, "unix", path)
+				}
+			})
+		}, nil
+	}
+}
+
+// dialConfig keeps the dialer of the transport apart from the DNS lookup and the timeout wrapping it,
+// so WithDialer, WithDialTimeout and the DNS options can be used in any order
+type dialConfig struct {
+	dial    func(ctx context.Context, network, addr string) (net.Conn, error)
+	timeout time.Duration
+	dns     *dnsConfig
+}
+
+// dialConfigs keeps the dialConfig of the clients being built by newClient
+var dialConfigs sync.Map
+
+// newClient runs funcs on a new client, then sets the dialer of its transport from the dialConfig they changed
+func newClient(funcs []ClientBuildFunc) *http.Client {
+	client := &http.Client{}
+	config := &dialConfig{}
+	dialConfigs.Store(client, config)
+	for _, f := range funcs {
+		f(client)
+	}
+	dialConfigs.Delete(client)
+	config.apply(client)
+	return client
+}
+
+// configureDial calls f with the dialConfig of the client
+// If the client is not built by newClient, the dialConfig is applied to its transport at once
+func configureDial(client *http.Client, f func(c *dialConfig)) {
+	if config, ok := dialConfigs.Load(client); ok {
+		f(config.(*dialConfig))
+		return
+	}
+	config := &dialConfig{}
+	f(config)
+	config.apply(client)
+}
+
+// apply sets the dialer of the transport, wrapped by the DNS lookup and then by the timeout
+// The dialer of the transport is kept if none is set, such as the one of WithTransport
+func (c *dialConfig) apply(client *http.Client) {
+	if c.dial == nil && c.timeout == 0 && c.dns == nil {
+		return
+	}
+	configureTransport(client, func(t *http.Transport) {
+		dial := c.dial
+		if dial == nil {
+			dial = t.DialContext
+		}
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		if c.dns != nil {
+			dial = dnsDial(c.dns, dial)
+		}
+		if timeout := c.timeout; timeout > 0 {
+			next := dial
+			dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				ctx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+				return next(ctx, network, addr)
+			}
+		}
+		t.DialContext = dial
+	})
+}
@@ -0,0 +1,89 @@
+package goya
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithUnixSocket(t *testing.T) {
+	// The path of a Unix socket is limited to about 100 bytes, which t.TempDir may exceed
+	dir, err := os.MkdirTemp("", "goya")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "goya.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Id":"` + r.URL.Path + `"}]`))
+	})}
+	go server.Serve(ln)
+	defer server.Close()
+
+	containers := Get[[]map[string]string]("http://unix/containers/json", NewOption(WithUnixSocket(path)))
+	if len(containers) != 1 || containers[0]["Id"] != "/containers/json" {
+		t.Errorf("containers got %v", containers)
+	}
+
+	dials := int32(0)
+	dialer := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}
+	containers = Get[[]map[string]string]("http://docker.test/containers/json", NewOption(WithDialer(dialer)))
+	if len(containers) != 1 || atomic.LoadInt32(&dials) != 1 {
+		t.Errorf("containers got %v with %v dials", containers, dials)
+	}
+}
+
+func TestDialOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("goya"))
+	}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	dialed := ""
+	dialer := WithDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	})
+	resolve := WithResolve(map[string]string{"goya.test:80": addr})
+	for name, opt := range map[string]*Option{
+		"dialer first":  NewOption(dialer, resolve),
+		"resolve first": NewOption(resolve, dialer),
+	} {
+		dialed = ""
+		if got, _ := RequestRaw(http.MethodGet, "http://goya.test/", opt).String(); got != "goya" || dialed != addr {
+			t.Errorf("%v got %v by dialing %v but want %v by dialing %v", name, got, dialed, "goya", addr)
+		}
+	}
+
+	hang := WithDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	timeout := WithDialTimeout(10 * time.Millisecond)
+	for name, opts := range map[string][]OptionFunc{
+		"dialer first":  {hang, timeout},
+		"timeout first": {timeout, hang},
+	} {
+		errs := []error{}
+		start := time.Now()
+		RequestRaw(http.MethodGet, server.URL, NewOption(append(opts, WithError(&errs))...))
+		if len(errs) == 0 || time.Since(start) > time.Second {
+			t.Errorf("%v got %v after %v", name, errs, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package goya
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IPPreference chooses the addresses of a host to connect to
+type IPPreference int
+
+const (
+	// IPAny connects to the addresses in the order of the resolver
+	IPAny IPPreference = iota
+	PreferIPv4
+	PreferIPv6
+	IPv4Only
+	IPv6Only
+)
+
+// DNSCache keeps the addresses of the hosts for a TTL, it can be shared by many requests
+type DNSCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// NewDNSCache returns a cache keeping the addresses for ttl
+// The resolvers of Go do not report the TTL of the records, so the same ttl is used for every host
+func NewDNSCache(ttl time.Duration) *DNSCache {
+	return &DNSCache{ttl: ttl, entries: map[string]dnsEntry{}}
+}
+
+// Clear removes the cached addresses
+func (c *DNSCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]dnsEntry{}
+}
+
+func (c *DNSCache) get(host string) ([]net.IPAddr, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[host]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, host)
+		return nil, false
+	}
+	return entry.addrs, true
+}
+
+func (c *DNSCache) set(host string, addrs []net.IPAddr) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+}
+
+// WithResolve will connect to the address of overrides instead of resolving host:port, like curl --resolve
+// An address without a port keeps the port of the request, such as {"example.com:443": "127.0.0.1"}
+func WithResolve(overrides map[string]string) OptionFunc {
+	return withDNS(func(c *dnsConfig) {
+		merged := map[string]string{}
+		for k, v := range c.overrides {
+			merged[k] = v
+		}
+		for k, v := range overrides {
+			merged[k] = v
+		}
+		c.overrides = merged
+	})
+}
+
+// WithResolver will resolve the hosts with resolver instead of net.DefaultResolver
+func WithResolver(resolver *net.Resolver) OptionFunc {
+	return withDNS(func(c *dnsConfig) {
+		c.resolver = resolver
+	})
+}
+
+// WithNameserver will resolve the hosts by the DNS server at addr, whose port is 53 if it is omitted
+func WithNameserver(addr string) OptionFunc {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	return WithResolver(&net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, network, addr)
+		},
+	})
+}
+
+// WithDNSCache will look up the addresses of the hosts in cache before resolving them
+func WithDNSCache(cache *DNSCache) OptionFunc {
+	return withDNS(func(c *dnsConfig) {
+		c.cache = cache
+	})
+}
+
+// WithIPPreference will order or filter the addresses of the hosts by their IP version
+func WithIPPreference(preference IPPreference) OptionFunc {
+	return withDNS(func(c *dnsConfig) {
+		c.preference = preference
+	})
+}
+
+// dnsConfig is kept by the dialConfig of the client, so the DNS options can be used in any order
+type dnsConfig struct {
+	overrides  map[string]string
+	resolver   *net.Resolver
+	cache      *DNSCache
+	preference IPPreference
+}
+
+// withDNS changes the dnsConfig of the client, whose dialer is wrapped with dnsDial when it is built
+func withDNS(f func(c *dnsConfig)) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			configureDial(client, func(c *dialConfig) {
+				if c.dns == nil {
+					c.dns = &dnsConfig{}
+				}
+				f(c.dns)
+			})
+		}, nil
+	}
+}
+
+// dnsDial resolves the host of addr by config and dials the addresses in turn with next
+func dnsDial(config *dnsConfig, next func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return next(ctx, network, addr)
+		}
+		if override, ok := config.overrides[addr]; ok {
+			if _, _, err := net.SplitHostPort(override); err != nil {
+				override = net.JoinHostPort(override, port)
+			}
+			return next(ctx, network, override)
+		}
+
+		addrs, err := config.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		addrs = config.preference.sort(addrs)
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no address of %v matches the IP preference", host)
+		}
+		for _, ip := range addrs {
+			var conn net.Conn
+			conn, err = next(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+func (c *dnsConfig) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if c.cache != nil {
+		if addrs, ok := c.cache.get(host); ok {
+			return addrs, nil
+		}
+	}
+	resolver := c.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache != nil {
+		c.cache.set(host, addrs)
+	}
+	return addrs, nil
+}
+
+func (p IPPreference) sort(addrs []net.IPAddr) []net.IPAddr {
+	if p == IPAny {
+		return addrs
+	}
+	v4, v6 := []net.IPAddr{}, []net.IPAddr{}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+	switch p {
+	case PreferIPv4:
+		return append(v4, v6...)
+	case PreferIPv6:
+		return append(v6, v4...)
+	case IPv4Only:
+		return v4
+	case IPv6Only:
+		return v6
+	}
+	return addrs
+}
@@ -0,0 +1,158 @@
+package goya
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serveDNS answers the A queries with 127.0.0.1 and the other queries with no record
+func serveDNS(t *testing.T, queries *int32) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(queries, 1)
+			// the question ends with a zero length label, followed by the type and the class
+			end := 12
+			for end < n && buf[end] != 0 {
+				end += int(buf[end]) + 1
+			}
+			question := buf[12 : end+5]
+			qtype := binary.BigEndian.Uint16(buf[end+1 : end+3])
+
+			resp := append([]byte{}, buf[0:2]...)
+			resp = append(resp, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+			resp = append(resp, question...)
+			if qtype == 1 {
+				resp[7] = 1
+				resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	target := "http://goya.test:" + port
+
+	got, _ := RequestRaw(http.MethodGet, target, NewOption(WithResolve(map[string]string{"goya.test:" + port: "127.0.0.1"}))).String()
+	if got != "goya.test:"+port {
+		t.Errorf("body with WithResolve got %v but want %v", got, "goya.test:"+port)
+	}
+	got, _ = RequestRaw(http.MethodGet, "http://goya.test/", NewOption(WithResolve(map[string]string{"goya.test:80": "127.0.0.1:" + port}))).String()
+	if got != "goya.test" {
+		t.Errorf("body with WithResolve and a port got %v but want %v", got, "goya.test")
+	}
+
+	queries := int32(0)
+	nameserver := serveDNS(t, &queries)
+	cache := NewDNSCache(time.Minute)
+	opt := NewOption(WithDNSCache(cache), WithIPPreference(IPv4Only), WithNameserver(nameserver))
+	for i := 0; i < 3; i++ {
+		if got, _ := RequestRaw(http.MethodGet, target, opt).String(); got != "goya.test:"+port {
+			t.Errorf("body with WithNameserver got %v but want %v", got, "goya.test:"+port)
+		}
+	}
+	// A and AAAA are queried once, then the addresses are cached
+	if n := atomic.LoadInt32(&queries); n != 2 {
+		t.Errorf("queries got %v but want %v", n, 2)
+	}
+
+	errs := []error{}
+	RequestRaw(http.MethodGet, target, NewOption(WithNameserver(nameserver), WithIPPreference(IPv6Only), WithError(&errs)))
+	if len(errs) == 0 {
+		t.Errorf("IPv6Only without AAAA records got no error")
+	}
+}
+
+func TestSessionDNS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	target := "http://goya.test:" + port
+
+	session := NewSession(WithResolve(map[string]string{"goya.test:" + port: "127.0.0.1"}))
+	errs := []error{}
+	got, _ := RequestRaw(http.MethodGet, target, NewOption(WithSession(session), WithError(&errs))).String()
+	if got != "goya.test:"+port || len(errs) != 0 {
+		t.Errorf("body with the WithResolve of the session got %v, %v but want %v", got, errs, "goya.test:"+port)
+	}
+
+	// the DNS options of the session wrap its dialer whatever the order is
+	dials := int32(0)
+	dialer := WithDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	})
+	resolve := WithResolve(map[string]string{"goya.test:" + port: "127.0.0.1"})
+	for _, session := range []*Session{NewSession(dialer, resolve), NewSession(resolve, dialer)} {
+		got, _ := RequestRaw(http.MethodGet, target, NewOption(WithSession(session))).String()
+		if got != "goya.test:"+port {
+			t.Errorf("body with the dialer of the session got %v but want %v", got, "goya.test:"+port)
+		}
+	}
+	if n := atomic.LoadInt32(&dials); n != 2 {
+		t.Errorf("dials got %v but want %v", n, 2)
+	}
+
+	queries := int32(0)
+	nameserver := serveDNS(t, &queries)
+	session = NewSession(WithNameserver(nameserver), WithIPPreference(IPv4Only), WithDNSCache(NewDNSCache(time.Minute)))
+	for i := 0; i < 2; i++ {
+		// the connection is not reused, so the host is resolved for each request
+		got, _ := RequestRaw(http.MethodGet, target, NewOption(WithSession(session), WithForceHeader("Connection", "close"))).String()
+		if got != "goya.test:"+port {
+			t.Errorf("body with the nameserver of the session got %v but want %v", got, "goya.test:"+port)
+		}
+	}
+	// A and AAAA are queried for the first request, the second one uses the cache of the session
+	if n := atomic.LoadInt32(&queries); n != 2 {
+		t.Errorf("queries got %v but want %v", n, 2)
+	}
+}
+
+func TestIPPreference(t *testing.T) {
+	addrs := []net.IPAddr{{IP: net.ParseIP("::1")}, {IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("::2")}}
+	ts := []struct {
+		preference IPPreference
+		want       string
+	}{
+		{IPAny, "::1 127.0.0.1 ::2"},
+		{PreferIPv4, "127.0.0.1 ::1 ::2"},
+		{PreferIPv6, "::1 ::2 127.0.0.1"},
+		{IPv4Only, "127.0.0.1"},
+		{IPv6Only, "::1 ::2"},
+	}
+	for _, tt := range ts {
+		got := []string{}
+		for _, addr := range tt.preference.sort(addrs) {
+			got = append(got, addr.String())
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("sort(%v) got %v but want %v", tt.preference, got, tt.want)
+		}
+	}
+}
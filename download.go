@@ -0,0 +1,352 @@
+package goya
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned by Download if the SHA-256 of the file is not the expected one
+var ErrChecksumMismatch = errors.New("goya: checksum mismatch")
+
+// DownloadOption changes how Download saves the file
+type DownloadOption func(c *downloadConfig)
+
+type downloadConfig struct {
+	progress func(written, total int64)
+	sha256   string
+	filename bool
+	noResume bool
+	parallel int
+	retries  int
+}
+
+// DownloadProgress calls f after each write with the bytes written so far, including the resumed ones,
+// and the total size, which is -1 if it is unknown
+func DownloadProgress(f func(written, total int64)) DownloadOption {
+	return func(c *downloadConfig) {
+		c.progress = f
+	}
+}
+
+// DownloadSHA256 verifies the file against the hex encoded SHA-256
+// Without it the Repr-Digest, Content-Digest or Digest header is verified if the server sends a sha-256 one
+func DownloadSHA256(sum string) DownloadOption {
+	return func(c *downloadConfig) {
+		c.sha256 = strings.ToLower(sum)
+	}
+}
+
+// DownloadFilename treats destPath as a directory and saves the file with the filename of Content-Disposition,
+// or the last element of the URL path if there is none
+func DownloadFilename() DownloadOption {
+	return func(c *downloadConfig) {
+		c.filename = true
+	}
+}
+
+// DownloadNoResume always downloads the whole file even if a partial one exists
+func DownloadNoResume() DownloadOption {
+	return func(c *downloadConfig) {
+		c.noResume = true
+	}
+}
+
+// DownloadParallel downloads the file with n concurrent Range requests of one chunk each
+// It falls back to a single request if the server does not support ranges or does not send the size of the file.
+// A failed chunk is sent again up to retries times, the parallel downloads are not resumed
+func DownloadParallel(n int, retries int) DownloadOption {
+	return func(c *downloadConfig) {
+		c.parallel = n
+		c.retries = retries
+	}
+}
+
+// DownloadResult describes a finished download
+type DownloadResult struct {
+	// Path is the path of the saved file
+	Path string
+	// Size is the size of the saved file
+	Size int64
+	// Resumed is the size of the partial file that was resumed, zero if it was downloaded from the start
+	Resumed int64
+	// Response is the response of the last request, its body has been consumed
+	Response *Response
+}
+
+// Download streams the body of a GET request to destPath
+//
+// The body is written to a temp file next to destPath which is renamed to destPath on success.
+// If the download fails the temp file is kept, and the next Download of the same URL resumes it
+// with Range and If-Range if the server sent an ETag or Last-Modified
+func Download(URL, destPath string, opt *Option, opts ...DownloadOption) (*DownloadResult, error) {
+	config := downloadConfig{}
+	for _, f := range opts {
+		f(&config)
+	}
+	d := &downloader{URL: URL, destPath: destPath, opt: opt, config: config}
+	if config.parallel > 1 {
+		return d.downloadParallel()
+	}
+	return d.download(true)
+}
+
+type downloader struct {
+	URL      string
+	destPath string
+	opt      *Option
+	config   downloadConfig
+}
+
+// partPath returns the temp file and its metadata file holding the validator for If-Range
+func (d *downloader) partPath() (string, string) {
+	part := d.destPath + ".part"
+	if d.config.filename {
+		sum := sha256.Sum256([]byte(d.URL))
+		part = filepath.Join(d.destPath, ".goya-"+hex.EncodeToString(sum[:8])+".part")
+	}
+	return part, part + ".meta"
+}
+
+func (d *downloader) download(resume bool) (*DownloadResult, error) {
+	part, meta := d.partPath()
+	offset, validator := int64(0), ""
+	if resume && !d.config.noResume {
+		offset, validator = partialDownload(part, meta)
+	}
+
+	next := []OptionFunc{}
+	if offset > 0 {
+		next = append(next, WithForceHeader("Range", fmt.Sprintf("bytes=%d-", offset)), WithForceHeader("If-Range", validator))
+	}
+	client := NewRequestClient(http.MethodGet, d.URL, d.opt.with(next...), nil)
+	resp := client.Do()
+	if errs := client.Errors(); errs != nil {
+		closeResponse(resp)
+		return nil, fmt.Errorf("Download %v : %w", d.URL, errs[0])
+	}
+	body := resp.RawResponse.Body
+	defer body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is not a prefix of the current file
+		os.Remove(part)
+		os.Remove(meta)
+		return d.download(false)
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			os.Remove(part)
+			os.Remove(meta)
+			return d.download(false)
+		}
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	default:
+		return nil, fmt.Errorf("Download %v : unexpected status code %d", d.URL, resp.StatusCode)
+	}
+	return d.save(resp, offset)
+}
+
+// save writes the body of resp to the temp file after its first offset bytes, and closes the body
+func (d *downloader) save(resp *Response, offset int64) (*DownloadResult, error) {
+	part, meta := d.partPath()
+	body := resp.RawResponse.Body
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(part), 0o755); err != nil {
+		return nil, err
+	}
+	if v := downloadValidator(resp.Header); v != "" {
+		if err := os.WriteFile(meta, []byte(v), 0o644); err != nil {
+			return nil, err
+		}
+	} else {
+		os.Remove(meta)
+	}
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(part, flag, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	if offset > 0 {
+		if err := hashFile(h, part); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	total := int64(-1)
+	if resp.RawResponse.ContentLength >= 0 {
+		total = offset + resp.RawResponse.ContentLength
+	}
+	var w io.Writer = f
+	if d.config.progress != nil {
+		w = &progressWriter{w: f, written: offset, total: total, f: d.config.progress}
+	}
+	written, err := io.Copy(io.MultiWriter(w, h), body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Download %v : %w", d.URL, err)
+	}
+
+	return d.finish(resp, hex.EncodeToString(h.Sum(nil)), offset+written, offset)
+}
+
+// closeResponse closes the body of resp if a response came back, such as a response with the errors of the options
+func closeResponse(resp *Response) {
+	if resp != nil && resp.RawResponse != nil && resp.RawResponse.Body != nil {
+		resp.RawResponse.Body.Close()
+	}
+}
+
+// finish verifies the checksum of the temp file and renames it
+func (d *downloader) finish(resp *Response, sum string, size, resumed int64) (*DownloadResult, error) {
+	part, meta := d.partPath()
+	if want := d.expectedSHA256(resp); want != "" && want != sum {
+		os.Remove(part)
+		os.Remove(meta)
+		return nil, fmt.Errorf("Download %v : %w, got %v but want %v", d.URL, ErrChecksumMismatch, sum, want)
+	}
+
+	dest := d.destPath
+	if d.config.filename {
+		dest = filepath.Join(d.destPath, downloadFilename(resp.Header.Get("Content-Disposition"), resp.RawResponse.Request.URL))
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return nil, err
+	}
+	os.Remove(meta)
+	return &DownloadResult{Path: dest, Size: size, Resumed: resumed, Response: resp}, nil
+}
+
+// expectedSHA256 returns the hex encoded SHA-256 of the whole file, empty if it is unknown
+func (d *downloader) expectedSHA256(resp *Response) string {
+	if d.config.sha256 != "" {
+		return d.config.sha256
+	}
+	headers := []string{"Repr-Digest", "Digest"}
+	// Content-Digest only covers the received range of a 206 response
+	if resp.StatusCode == http.StatusOK {
+		headers = append(headers, "Content-Digest")
+	}
+	for _, header := range headers {
+		if sum := digestSHA256(resp.Header.Values(header)); sum != "" {
+			return sum
+		}
+	}
+	return ""
+}
+
+// digestSHA256 returns the hex encoded sha-256 of the headers in RFC 9530 (sha-256=:base64:) or RFC 3230 (SHA-256=base64) format
+func digestSHA256(values []string) string {
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			name, sum, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok || !strings.EqualFold(name, "sha-256") {
+				continue
+			}
+			bts, err := base64.StdEncoding.DecodeString(strings.Trim(sum, ":"))
+			if err != nil || len(bts) != sha256.Size {
+				continue
+			}
+			return hex.EncodeToString(bts)
+		}
+	}
+	return ""
+}
+
+// partialDownload returns the size of the partial file and its validator, zero if it can not be resumed
+func partialDownload(part, meta string) (int64, string) {
+	info, err := os.Stat(part)
+	if err != nil || info.Size() == 0 {
+		return 0, ""
+	}
+	validator, err := os.ReadFile(meta)
+	if err != nil || len(bytes.TrimSpace(validator)) == 0 {
+		return 0, ""
+	}
+	return info.Size(), string(bytes.TrimSpace(validator))
+}
+
+// downloadValidator returns the validator for If-Range, weak ETags can not be used
+func downloadValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+func contentRangeStart(contentRange string) (int64, bool) {
+	spec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
+
+func hashFile(h hash.Hash, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// downloadFilename returns a safe filename from Content-Disposition, or from the URL path
+func downloadFilename(contentDisposition string, u *url.URL) string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(contentDisposition); err == nil {
+		name = params["filename"]
+	}
+	if name == "" && u != nil {
+		name = path.Base(u.Path)
+	}
+	// The filename comes from the server, so the directories are dropped
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" || name == "" {
+		return "download"
+	}
+	return name
+}
+
+// progressWriter reports the bytes written to f
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	total   int64
+	f       func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.f(p.written, p.total)
+	return n, err
+}
@@ -0,0 +1,157 @@
+package goya
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// downloadParallel probes the server with a one byte Range request,
+// the result has the response of the probe because the chunks only have a part of the file
+func (d *downloader) downloadParallel() (*DownloadResult, error) {
+	client := NewRequestClient(http.MethodGet, d.URL, d.opt.with(WithForceHeader("Range", "bytes=0-0")), nil)
+	probe := client.Do()
+	if errs := client.Errors(); errs != nil {
+		closeResponse(probe)
+		return nil, fmt.Errorf("Download %v : %w", d.URL, errs[0])
+	}
+	// A server without the support of ranges sends the whole file, which is saved as a single stream
+	if probe.StatusCode == http.StatusOK {
+		return d.save(probe, 0)
+	}
+	total, ok := contentRangeSize(probe.Header.Get("Content-Range"))
+	if probe.StatusCode != http.StatusPartialContent || !ok || total == 0 {
+		probe.RawResponse.Body.Close()
+		return d.download(true)
+	}
+	probe.Bytes()
+
+	part, _ := d.partPath()
+	if err := os.MkdirAll(filepath.Dir(part), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(total); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	n := min(int64(d.config.parallel), total)
+	size := (total + n - 1) / n
+	progress := &chunkProgress{total: total, f: d.config.progress}
+	validator := downloadValidator(probe.Header)
+	errs := make([]error, n)
+	wg := sync.WaitGroup{}
+	for i := int64(0); i < n; i++ {
+		start, end := i*size, min((i+1)*size, total)-1
+		if start > end {
+			continue
+		}
+		wg.Add(1)
+		go func(i, start, end int64) {
+			defer wg.Done()
+			errs[i] = d.downloadChunk(f, start, end, validator, progress)
+		}(i, start, end)
+	}
+	wg.Wait()
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("Download %v : %w", d.URL, err)
+		}
+	}
+
+	h := sha256.New()
+	if err := hashFile(h, part); err != nil {
+		return nil, err
+	}
+	return d.finish(probe, hex.EncodeToString(h.Sum(nil)), total, 0)
+}
+
+// downloadChunk writes the bytes from start to end of the file to f, sending the request again if it fails
+func (d *downloader) downloadChunk(f io.WriterAt, start, end int64, validator string, progress *chunkProgress) error {
+	next := []OptionFunc{WithForceHeader("Range", fmt.Sprintf("bytes=%d-%d", start, end))}
+	if validator != "" {
+		// The server sends the whole file instead of the range if the file has changed
+		next = append(next, WithForceHeader("If-Range", validator))
+	}
+	var err error
+	for attempt := 1; attempt <= d.config.retries+1; attempt++ {
+		opt := d.opt.with(append(next, withAttempt(attempt, err))...)
+		var written int64
+		written, err = d.fetchChunk(opt, io.NewOffsetWriter(f, start), start, end, progress)
+		if err == nil {
+			return nil
+		}
+		progress.add(-written)
+	}
+	return fmt.Errorf("chunk %d-%d : %w", start, end, err)
+}
+
+func (d *downloader) fetchChunk(opt *Option, w io.Writer, start, end int64, progress *chunkProgress) (int64, error) {
+	client := NewRequestClient(http.MethodGet, d.URL, opt, nil)
+	resp := client.Do()
+	if errs := client.Errors(); errs != nil {
+		closeResponse(resp)
+		return 0, errs[0]
+	}
+	defer resp.RawResponse.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if got, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || got != start {
+		return 0, fmt.Errorf("unexpected Content-Range %v", resp.Header.Get("Content-Range"))
+	}
+	return io.CopyN(&chunkWriter{w: w, progress: progress}, resp.RawResponse.Body, end-start+1)
+}
+
+// contentRangeSize returns the complete length of Content-Range, false if it is unknown
+func contentRangeSize(contentRange string) (int64, bool) {
+	_, size, ok := strings.Cut(contentRange, "/")
+	if !ok || size == "*" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	return n, err == nil
+}
+
+// chunkProgress sums the bytes written by the chunks, f is called by one chunk at a time
+type chunkProgress struct {
+	mu      sync.Mutex
+	written int64
+	total   int64
+	f       func(written, total int64)
+}
+
+func (p *chunkProgress) add(n int64) {
+	if p.f == nil || n == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.written += n
+	p.f(p.written, p.total)
+}
+
+type chunkWriter struct {
+	w        io.Writer
+	progress *chunkProgress
+}
+
+func (c *chunkWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.progress.add(int64(n))
+	return n, err
+}
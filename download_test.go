@@ -0,0 +1,230 @@
+package goya
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func downloadHandler(content []byte, ranges *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ranges != nil {
+			*ranges = append(*ranges, r.Header.Get("Range"))
+		}
+		sum := sha256.Sum256(content)
+		w.Header().Set("ETag", `"goya"`)
+		w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+		w.Header().Set("Content-Disposition", `attachment; filename="../goya.bin"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	})
+}
+
+func TestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "file.bin")
+	ranges := []string{}
+	progress := int64(0)
+
+	result, err := Download("http://goya.test/file", dest, NewOption(WithHandler(downloadHandler(content, &ranges))),
+		DownloadProgress(func(written, total int64) {
+			if total != int64(len(content)) {
+				t.Errorf("total got %v but want %v", total, len(content))
+			}
+			progress = written
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, content) || result.Size != int64(len(content)) || result.Path != dest {
+		t.Errorf("result got %v %v, file size got %v", result.Path, result.Size, len(got))
+	}
+	if progress != int64(len(content)) {
+		t.Errorf("progress got %v but want %v", progress, len(content))
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("the temp file is not removed : %v", err)
+	}
+
+	// resume a partial download
+	os.WriteFile(dest+".part", content[:4000], 0o644)
+	os.WriteFile(dest+".part.meta", []byte(`"goya"`), 0o644)
+	ranges = ranges[:0]
+	result, err = Download("http://goya.test/file", dest, NewOption(WithHandler(downloadHandler(content, &ranges))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = os.ReadFile(dest)
+	if !bytes.Equal(got, content) || result.Resumed != 4000 {
+		t.Errorf("resumed got %v, file size got %v", result.Resumed, len(got))
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=4000-" {
+		t.Errorf("ranges got %v", ranges)
+	}
+
+	// the partial file is from another version
+	os.WriteFile(dest+".part", content[:4000], 0o644)
+	os.WriteFile(dest+".part.meta", []byte(`"old"`), 0o644)
+	result, err = Download("http://goya.test/file", dest, NewOption(WithHandler(downloadHandler(content, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = os.ReadFile(dest); !bytes.Equal(got, content) || result.Resumed != 0 {
+		t.Errorf("resumed got %v, file size got %v", result.Resumed, len(got))
+	}
+}
+
+func TestDownloadChecksum(t *testing.T) {
+	content := []byte(strings.Repeat("goya", 100))
+	dir := t.TempDir()
+	sum := sha256.Sum256(content)
+
+	result, err := Download("http://goya.test/dir/file", dir, NewOption(WithHandler(downloadHandler(content, nil))),
+		DownloadFilename(), DownloadSHA256(hex.EncodeToString(sum[:])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Path != filepath.Join(dir, "goya.bin") {
+		t.Errorf("result.Path got %v but want %v", result.Path, filepath.Join(dir, "goya.bin"))
+	}
+
+	dest := filepath.Join(dir, "file.bin")
+	_, err = Download("http://goya.test/file", dest, NewOption(WithHandler(downloadHandler(content, nil))), DownloadSHA256(strings.Repeat("0", 64)))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("err got %v but want %v", err, ErrChecksumMismatch)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("the file is saved although the checksum mismatches")
+	}
+
+	corrupted := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]))
+		w.Write([]byte("corrupted"))
+	})
+	if _, err := Download("http://goya.test/file", dest, NewOption(WithHandler(corrupted))); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("err got %v but want %v", err, ErrChecksumMismatch)
+	}
+
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) })
+	if _, err := Download("http://goya.test/file", dest, NewOption(WithHandler(notFound))); err == nil {
+		t.Errorf("err got nil for status 404")
+	}
+}
+
+func TestDownloadFilename(t *testing.T) {
+	ts := []struct {
+		contentDisposition string
+		URL                string
+		want               string
+	}{
+		{`attachment; filename="report.pdf"`, "http://goya.test/a", "report.pdf"},
+		{`attachment; filename*=UTF-8''%E6%96%87%E4%BB%B6.txt`, "http://goya.test/a", "文件.txt"},
+		{`attachment; filename="..\..\evil.sh"`, "http://goya.test/a", "evil.sh"},
+		{"", "http://goya.test/files/data.csv?x=1", "data.csv"},
+		{"", "http://goya.test/", "download"},
+	}
+	for _, tt := range ts {
+		req, _ := http.NewRequest(http.MethodGet, tt.URL, nil)
+		if got := downloadFilename(tt.contentDisposition, req.URL); got != tt.want {
+			t.Errorf("downloadFilename(%q) got %v but want %v", tt.contentDisposition, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadParallel(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	dest := filepath.Join(t.TempDir(), "file.bin")
+	mu := sync.Mutex{}
+	ranges := []string{}
+	failed := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		fail := r.Header.Get("Range") == "bytes=2500-4999" && !failed
+		failed = failed || fail
+		mu.Unlock()
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		downloadHandler(content, nil).ServeHTTP(w, r)
+	})
+
+	progress := int64(0)
+	result, err := Download("http://goya.test/file", dest, NewOption(WithHandler(handler)), DownloadParallel(4, 1),
+		DownloadProgress(func(written, total int64) { progress = written }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, content) || result.Size != int64(len(content)) {
+		t.Errorf("result.Size got %v, file size got %v", result.Size, len(got))
+	}
+	if progress != int64(len(content)) {
+		t.Errorf("progress got %v but want %v", progress, len(content))
+	}
+	sort.Strings(ranges)
+	want := []string{"bytes=0-0", "bytes=0-2499", "bytes=2500-4999", "bytes=2500-4999", "bytes=5000-7499", "bytes=7500-9999"}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ranges got %v but want %v", ranges, want)
+	}
+
+	// the chunk keeps failing
+	failed = false
+	if _, err := Download("http://goya.test/file", dest, NewOption(WithHandler(handler)), DownloadParallel(4, 0)); err == nil {
+		t.Errorf("err got nil but the chunk failed")
+	}
+
+	// the server does not support ranges, the response of the probe is saved
+	requests, served := 0, 0
+	noRange := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		n, _ := w.Write(content)
+		served += n
+	})
+	result, err = Download("http://goya.test/file", dest, NewOption(WithHandler(noRange)), DownloadParallel(4, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = os.ReadFile(dest); !bytes.Equal(got, content) || result.Size != int64(len(content)) {
+		t.Errorf("result.Size got %v, file size got %v", result.Size, len(got))
+	}
+	if requests != 1 || served != len(content) {
+		t.Errorf("server without ranges got %v requests and %v bytes but want 1 and %v", requests, served, len(content))
+	}
+}
+
+func TestDownloadClosesBody(t *testing.T) {
+	content := bytes.Repeat([]byte("goya"), 1<<16)
+	for _, opts := range [][]DownloadOption{nil, {DownloadParallel(2, 0)}} {
+		done := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer close(done)
+			// the write only returns when the body is read or closed by the client
+			w.Write(content)
+		})
+		dest := filepath.Join(t.TempDir(), "file.bin")
+		// the request is sent, but the option reports an error
+		opt := NewOption(WithHandler(handler), WithDownloadLimit(0))
+		if _, err := Download("http://goya.test/file", dest, opt, opts...); err == nil {
+			t.Error("Download with an invalid option got no error")
+		}
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Errorf("the body is not closed after the error with %d options", len(opts))
+		}
+	}
+}
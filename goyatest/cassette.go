@@ -0,0 +1,359 @@
+package goyatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// Redacted replaces the secrets before the cassette is written
+const Redacted = "REDACTED"
+
+type Mode int
+
+const (
+	// ModeAuto records when the cassette file does not exist, otherwise it is the same as ModeStrictReplay
+	ModeAuto Mode = iota
+	// ModeRecord sends every request and overwrites the cassette
+	ModeRecord
+	// ModeReplay replays the recorded interactions, the unmatched requests are sent and appended to the cassette
+	ModeReplay
+	// ModeStrictReplay replays the recorded interactions and fails the unmatched requests
+	ModeStrictReplay
+)
+
+// ErrNoInteraction is returned in strict replay mode when no recorded interaction matches the request
+var ErrNoInteraction = errors.New("goyatest: no recorded interaction matches the request")
+
+// Cassette is the file format of the recorded interactions
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body is written as a string if it is valid UTF-8, otherwise as base64 in {"base64": "..."}
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string][]byte{"base64": b})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = []byte(text)
+		return nil
+	}
+	encoded := map[string][]byte{}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	*b = encoded["base64"]
+	return nil
+}
+
+// Matcher reports whether the incoming request got matches the recorded one
+// got has already been redacted like the recorded requests
+type Matcher func(got, recorded *RecordedRequest) bool
+
+// MatchMethod matches the method of the requests
+func MatchMethod(got, recorded *RecordedRequest) bool {
+	return got.Method == recorded.Method
+}
+
+// MatchURL matches the URL of the requests, the order of the query params is ignored
+func MatchURL(got, recorded *RecordedRequest) bool {
+	g, err := url.Parse(got.URL)
+	if err != nil {
+		return got.URL == recorded.URL
+	}
+	r, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	return g.Scheme == r.Scheme && g.Host == r.Host && g.Path == r.Path && g.Query().Encode() == r.Query().Encode()
+}
+
+// MatchBody matches the body of the requests, JSON bodies are compared regardless of the order of the keys
+func MatchBody(got, recorded *RecordedRequest) bool {
+	if bytes.Equal(got.Body, recorded.Body) {
+		return true
+	}
+	var g, r any
+	if json.Unmarshal(got.Body, &g) != nil || json.Unmarshal(recorded.Body, &r) != nil {
+		return false
+	}
+	gb, _ := json.Marshal(g)
+	rb, _ := json.Marshal(r)
+	return bytes.Equal(gb, rb)
+}
+
+// RecorderOption changes the behavior of a Recorder
+type RecorderOption func(r *Recorder)
+
+// WithRealTransport sets the transport used to send the requests that are recorded, http.DefaultTransport by default
+func WithRealTransport(transport http.RoundTripper) RecorderOption {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithMatchers replaces the matchers, MatchMethod and MatchURL by default
+func WithMatchers(matchers ...Matcher) RecorderOption {
+	return func(r *Recorder) {
+		r.matchers = matchers
+	}
+}
+
+// WithRedactedHeaders adds the headers to redact
+// Authorization, Proxy-Authorization, Cookie and Set-Cookie are always redacted
+func WithRedactedHeaders(headers ...string) RecorderOption {
+	return func(r *Recorder) {
+		r.headers = append(r.headers, headers...)
+	}
+}
+
+// WithRedactedQuery adds the query params to redact, such as api_key or token
+func WithRedactedQuery(params ...string) RecorderOption {
+	return func(r *Recorder) {
+		r.params = append(r.params, params...)
+	}
+}
+
+// Recorder is an http.RoundTripper that records the real interactions to a cassette file and replays them afterwards
+//
+//	recorder, err := goyatest.NewRecorder("testdata/users.json", goyatest.ModeAuto)
+//	defer recorder.Stop()
+//	goya.Get[[]User](URL, goya.NewOption(goya.WithTransport(recorder)))
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	matchers  []Matcher
+	headers   []string
+	params    []string
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+	changed  bool
+}
+
+// NewRecorder loads the cassette at path unless it records, the cassette is written by Stop()
+func NewRecorder(path string, mode Mode, opts ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		matchers:  []Matcher{MatchMethod, MatchURL},
+		headers:   []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+		params:    []string{},
+		cassette:  &Cassette{Interactions: []*Interaction{}},
+	}
+	for _, f := range opts {
+		f(r)
+	}
+
+	if r.mode == ModeAuto {
+		r.mode = ModeStrictReplay
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			r.mode = ModeRecord
+		}
+	}
+	if r.mode == ModeRecord {
+		return r, nil
+	}
+
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bts, r.cassette); err != nil {
+		return nil, fmt.Errorf("cassette %v is invalid : %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Mode returns the mode in use, ModeAuto has been resolved to ModeRecord or ModeStrictReplay
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Cassette returns the interactions recorded or loaded so far
+func (r *Recorder) Cassette() *Cassette {
+	return r.cassette
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	got := r.redactRequest(req, body)
+
+	if r.mode != ModeRecord {
+		if interaction := r.find(got); interaction != nil {
+			resp := NewResponse(req, interaction.Response.StatusCode, interaction.Response.Header.Clone(), interaction.Response.Body)
+			return resp, nil
+		}
+		if r.mode == ModeStrictReplay {
+			return nil, fmt.Errorf("%w : %v %v", ErrNoInteraction, got.Method, got.URL)
+		}
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: *got,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+			Body:       respBody,
+		},
+	})
+	r.used = append(r.used, true)
+	r.changed = true
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Stop writes the cassette if new interactions have been recorded
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.changed {
+		return nil
+	}
+	bts, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, bts, 0o644); err != nil {
+		return err
+	}
+	r.changed = false
+	return nil
+}
+
+// find returns the first unused interaction that matches got
+// If all matched interactions have been used, the first one is replayed again
+func (r *Recorder) find(got *RecordedRequest) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	first := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !r.match(got, &interaction.Request) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return interaction
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	return r.cassette.Interactions[first]
+}
+
+func (r *Recorder) match(got, recorded *RecordedRequest) bool {
+	for _, m := range r.matchers {
+		if !m(got, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Recorder) redactRequest(req *http.Request, body []byte) *RecordedRequest {
+	u := *req.URL
+	if len(r.params) > 0 {
+		querys := u.Query()
+		for _, p := range r.params {
+			if querys.Has(p) {
+				querys.Set(p, Redacted)
+			}
+		}
+		u.RawQuery = querys.Encode()
+	}
+	return &RecordedRequest{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: r.redactHeader(req.Header),
+		Body:   body,
+	}
+}
+
+func (r *Recorder) redactHeader(header http.Header) http.Header {
+	result := header.Clone()
+	for _, h := range r.headers {
+		values := result.Values(h)
+		if len(values) == 0 {
+			continue
+		}
+		result.Del(h)
+		for range values {
+			result.Add(h, Redacted)
+		}
+	}
+	return result
+}
+
+func (m Mode) String() string {
+	switch m {
+	case ModeAuto:
+		return "auto"
+	case ModeRecord:
+		return "record"
+	case ModeReplay:
+		return "replay"
+	case ModeStrictReplay:
+		return "strict replay"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
@@ -0,0 +1,105 @@
+package goyatest_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sagayosa/goya"
+	"github.com/sagayosa/goya/goyatest"
+)
+
+func TestRecorder(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-session"})
+		w.Write([]byte(`{"name":"` + r.URL.Query().Get("name") + `","id":1}`))
+	}))
+	path := filepath.Join(t.TempDir(), "cassettes", "users.json")
+	opt := func(recorder *goyatest.Recorder, name string) *goya.Option {
+		return goya.NewOption(
+			goya.WithTransport(recorder),
+			goya.WithParams(map[string]string{"name": name, "token": "secret-token"}),
+			goya.WithForceHeader("Authorization", "Bearer secret-auth"),
+		)
+	}
+
+	recorder, err := goyatest.NewRecorder(path, goyatest.ModeAuto, goyatest.WithRedactedQuery("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Mode() != goyatest.ModeRecord {
+		t.Errorf("Mode got %v but want %v", recorder.Mode(), goyatest.ModeRecord)
+	}
+	got := goya.Get[user](server.URL, opt(recorder, "goya"))
+	if got.Name != "goya" {
+		t.Errorf("recorded got %v but want %v", got.Name, "goya")
+	}
+	if err := recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-token", "secret-auth", "secret-session"} {
+		if strings.Contains(string(bts), secret) {
+			t.Errorf("cassette contains %v", secret)
+		}
+	}
+
+	recorder, err = goyatest.NewRecorder(path, goyatest.ModeAuto, goyatest.WithRedactedQuery("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Mode() != goyatest.ModeStrictReplay {
+		t.Errorf("Mode got %v but want %v", recorder.Mode(), goyatest.ModeStrictReplay)
+	}
+	got = goya.Get[user](server.URL, opt(recorder, "goya"))
+	if got.Name != "goya" || got.ID != 1 {
+		t.Errorf("replayed got %v but want %v", got, user{"goya", 1})
+	}
+	if calls != 1 {
+		t.Errorf("server got %v calls but want %v", calls, 1)
+	}
+
+	errs := []error{}
+	goya.Get[user](server.URL, goya.NewOption(goya.WithTransport(recorder), goya.WithParams(map[string]string{"name": "other"}), goya.WithError(&errs)))
+	if len(errs) == 0 || !errors.Is(errs[0], goyatest.ErrNoInteraction) {
+		t.Errorf("unmatched request got %v but want %v", errs, goyatest.ErrNoInteraction)
+	}
+}
+
+func TestRecorderMatchBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "body.json")
+
+	recorder, _ := goyatest.NewRecorder(path, goyatest.ModeRecord)
+	goya.RequestRaw(http.MethodPost, server.URL, goya.NewOption(goya.WithTransport(recorder), goya.WithJson(map[string]any{"a": 1, "b": 2})))
+	recorder.Stop()
+
+	recorder, err := goyatest.NewRecorder(path, goyatest.ModeReplay, goyatest.WithMatchers(goyatest.MatchMethod, goyatest.MatchURL, goyatest.MatchBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := goya.RequestRaw(http.MethodPost, server.URL, goya.NewOption(goya.WithTransport(recorder), goya.WithForceHeader("Content-Type", "application/json"), goya.WithJson(map[string]any{"b": 2, "a": 1})))
+	body, _ := resp.String()
+	if body != http.MethodPost {
+		t.Errorf("body got %v but want %v", body, http.MethodPost)
+	}
+	// The body does not match, so the request is sent and appended in replay mode
+	goya.RequestRaw(http.MethodPost, server.URL, goya.NewOption(goya.WithTransport(recorder), goya.WithJson(map[string]any{"a": 2})))
+	if len(recorder.Cassette().Interactions) != 2 {
+		t.Errorf("Interactions got %v but want %v", len(recorder.Cassette().Interactions), 2)
+	}
+}
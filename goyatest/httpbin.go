@@ -0,0 +1,317 @@
+package goyatest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDelay is the longest delay accepted by /delay/{n}, the same as httpbin.org
+const maxDelay = 10 * time.Second
+
+// NewHTTPBin starts an httptest.Server that implements the httpbin.org endpoints with the same JSON shapes
+// so that the requests can be tested offline, remember to Close() it
+//
+//	/get /post /put /patch /delete /anything/{path}
+//	/headers /ip /user-agent
+//	/status/{code} /delay/{n} /redirect/{n}
+//	/cookies /cookies/set?name=value /cookies/delete?name
+//	/basic-auth/{user}/{passwd} /stream/{n} /gzip
+func NewHTTPBin() *httptest.Server {
+	return httptest.NewServer(NewHTTPBinHandler())
+}
+
+// NewHTTPBinHandler returns the handler used by NewHTTPBin, it can be mounted on your own server
+func NewHTTPBinHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get", methods(handleEcho, http.MethodGet, http.MethodHead))
+	mux.HandleFunc("/post", methods(handleEcho, http.MethodPost))
+	mux.HandleFunc("/put", methods(handleEcho, http.MethodPut))
+	mux.HandleFunc("/patch", methods(handleEcho, http.MethodPatch))
+	mux.HandleFunc("/delete", methods(handleEcho, http.MethodDelete))
+	mux.HandleFunc("/anything", handleEcho)
+	mux.HandleFunc("/anything/", handleEcho)
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"headers": headers(r)})
+	})
+	mux.HandleFunc("/ip", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"origin": origin(r)})
+	})
+	mux.HandleFunc("/user-agent", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"user-agent": r.UserAgent()})
+	})
+	mux.HandleFunc("/status/", handleStatus)
+	mux.HandleFunc("/delay/", handleDelay)
+	mux.HandleFunc("/redirect/", handleRedirect)
+	mux.HandleFunc("/cookies", handleCookies)
+	mux.HandleFunc("/cookies/set", handleSetCookies)
+	mux.HandleFunc("/cookies/delete", handleDeleteCookies)
+	mux.HandleFunc("/basic-auth/", handleBasicAuth)
+	mux.HandleFunc("/stream/", handleStream)
+	mux.HandleFunc("/gzip", handleGzip)
+	return mux
+}
+
+// methods only allows the given methods, the others get 405 like httpbin.org
+func methods(handler http.HandlerFunc, allowed ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, m := range allowed {
+			if r.Method == m {
+				handler(w, r)
+				return
+			}
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleEcho replies with the shape of BasicGetResponse for GET and BasicPostResponse for the other methods
+func handleEcho(w http.ResponseWriter, r *http.Request) {
+	result, err := echo(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func echo(r *http.Request) (map[string]any, error) {
+	result := map[string]any{
+		"args":    flatten(r.URL.Query()),
+		"headers": headers(r),
+		"origin":  origin(r),
+		"url":     fullURL(r),
+	}
+	if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/anything") {
+		return result, nil
+	}
+	if strings.HasPrefix(r.URL.Path, "/anything") {
+		result["method"] = r.Method
+	}
+
+	form := map[string]any{}
+	files := map[string]any{}
+	data := ""
+	var jsonData any
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		form = flatten(r.MultipartForm.Value)
+		for name, headers := range r.MultipartForm.File {
+			for _, h := range headers {
+				f, err := h.Open()
+				if err != nil {
+					return nil, err
+				}
+				bts, err := io.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return nil, err
+				}
+				files[name] = string(bts)
+			}
+		}
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		form = flatten(r.PostForm)
+	default:
+		bts, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		data = string(bts)
+		if json.Unmarshal(bts, &jsonData) != nil {
+			jsonData = nil
+		}
+	}
+	result["data"] = data
+	result["files"] = files
+	result["form"] = form
+	result["json"] = jsonData
+	return result, nil
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	code, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/status/"))
+	if err != nil || code < 100 || code > 999 {
+		http.Error(w, "invalid status code", http.StatusBadRequest)
+		return
+	}
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		w.Header().Set("Location", "/redirect/1")
+	case http.StatusUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Basic realm="Fake Realm"`)
+	}
+	w.WriteHeader(code)
+}
+
+func handleDelay(w http.ResponseWriter, r *http.Request) {
+	seconds, err := strconv.ParseFloat(strings.TrimPrefix(r.URL.Path, "/delay/"), 64)
+	if err != nil || seconds < 0 {
+		http.Error(w, "invalid delay", http.StatusBadRequest)
+		return
+	}
+	delay := min(time.Duration(seconds*float64(time.Second)), maxDelay)
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+	handleEcho(w, r)
+}
+
+func handleRedirect(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/redirect/"))
+	if err != nil || n < 1 {
+		http.Error(w, "invalid redirect count", http.StatusBadRequest)
+		return
+	}
+	location := "/get"
+	if n > 1 {
+		location = fmt.Sprintf("/redirect/%d", n-1)
+	}
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+func handleCookies(w http.ResponseWriter, r *http.Request) {
+	cookies := map[string]string{}
+	for _, c := range r.Cookies() {
+		cookies[c.Name] = c.Value
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"cookies": cookies})
+}
+
+func handleSetCookies(w http.ResponseWriter, r *http.Request) {
+	for name, values := range r.URL.Query() {
+		for _, v := range values {
+			http.SetCookie(w, &http.Cookie{Name: name, Value: v, Path: "/"})
+		}
+	}
+	http.Redirect(w, r, "/cookies", http.StatusFound)
+}
+
+func handleDeleteCookies(w http.ResponseWriter, r *http.Request) {
+	for name := range r.URL.Query() {
+		http.SetCookie(w, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1, Expires: time.Unix(0, 0)})
+	}
+	http.Redirect(w, r, "/cookies", http.StatusFound)
+}
+
+func handleBasicAuth(w http.ResponseWriter, r *http.Request) {
+	user, passwd, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/basic-auth/"), "/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	gotUser, gotPasswd, ok := r.BasicAuth()
+	if !ok || gotUser != user || gotPasswd != passwd {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Fake Realm"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"authenticated": true, "user": user})
+}
+
+// handleStream writes n JSON lines and flushes after each one
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/stream/"))
+	if err != nil || n < 0 {
+		http.Error(w, "invalid stream count", http.StatusBadRequest)
+		return
+	}
+	n = min(n, 100)
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	for i := 0; i < n; i++ {
+		encoder.Encode(map[string]any{
+			"id":      i,
+			"args":    flatten(r.URL.Query()),
+			"headers": headers(r),
+			"origin":  origin(r),
+			"url":     fullURL(r),
+		})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func handleGzip(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Encoding", "gzip")
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	json.NewEncoder(gz).Encode(map[string]any{
+		"gzipped": true,
+		"headers": headers(r),
+		"method":  r.Method,
+		"origin":  origin(r),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(data)
+}
+
+// flatten converts the values with one element to a string like httpbin.org
+func flatten(values map[string][]string) map[string]any {
+	result := map[string]any{}
+	for k, v := range values {
+		if len(v) == 1 {
+			result[k] = v[0]
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// headers returns the request headers, the values of the same header are joined by commas
+func headers(r *http.Request) map[string]string {
+	result := map[string]string{"Host": r.Host}
+	for k, v := range r.Header {
+		result[k] = strings.Join(v, ",")
+	}
+	if _, ok := result["Content-Length"]; !ok && r.ContentLength > 0 {
+		result["Content-Length"] = strconv.FormatInt(r.ContentLength, 10)
+	}
+	return result
+}
+
+func origin(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func fullURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	return u.String()
+}
@@ -0,0 +1,104 @@
+package goyatest_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagayosa/goya"
+	"github.com/sagayosa/goya/goyatest"
+)
+
+func TestHTTPBinEcho(t *testing.T) {
+	server := goyatest.NewHTTPBin()
+	defer server.Close()
+
+	get := goya.Get[goya.BasicGetResponse](server.URL+"/get", map[string]string{"name": "goya"})
+	if get.URL != server.URL+"/get?name=goya" {
+		t.Errorf("URL got %v but want %v", get.URL, server.URL+"/get?name=goya")
+	}
+	if args, _ := get.Args.(map[string]any); args["name"] != "goya" {
+		t.Errorf("Args got %v but want %v", get.Args, map[string]any{"name": "goya"})
+	}
+
+	post := goya.Post[goya.BasicPostResponse](server.URL+"/post", goya.NewOption(goya.WithForm(map[string]any{"name": "goya", "numbers": []string{"1", "2"}})))
+	form, _ := post.Form.(map[string]any)
+	if form["name"] != "goya" || len(form["numbers"].([]any)) != 2 {
+		t.Errorf("Form got %v", post.Form)
+	}
+
+	put := goya.Put[goya.BasicPostResponse](server.URL+"/put", map[string]string{"name": "goya"})
+	if put.Data != `{"name":"goya"}` || put.Headers.ContentType != "application/json" {
+		t.Errorf("Data got %v with Content-Type %v", put.Data, put.Headers.ContentType)
+	}
+
+	resp := goya.RequestRaw(http.MethodGet, server.URL+"/post", goya.NewOption())
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("StatusCode got %v but want %v", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+
+	anything := goya.Request[map[string]any](http.MethodPatch, server.URL+"/anything/x", goya.NewOption(goya.WithJson(map[string]int{"id": 1})))
+	if anything["method"] != http.MethodPatch || anything["json"].(map[string]any)["id"] != float64(1) {
+		t.Errorf("anything got %v", anything)
+	}
+}
+
+func TestHTTPBinEndpoints(t *testing.T) {
+	server := goyatest.NewHTTPBin()
+	defer server.Close()
+
+	resp := goya.RequestRaw(http.MethodGet, server.URL+"/status/418", goya.NewOption())
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("status got %v but want %v", resp.StatusCode, http.StatusTeapot)
+	}
+
+	errs := []error{}
+	goya.Get[any](server.URL+"/delay/1", goya.NewOption(goya.WithTimeout(50*time.Millisecond), goya.WithError(&errs)))
+	if len(errs) == 0 {
+		t.Error("delay should time out")
+	}
+
+	redirected := goya.Get[goya.BasicGetResponse](server.URL+"/redirect/3", nil)
+	if redirected.URL != server.URL+"/get" {
+		t.Errorf("redirect URL got %v but want %v", redirected.URL, server.URL+"/get")
+	}
+
+	jar, _ := cookiejar.New(nil)
+	withJar := func(client *http.Client) { client.Jar = jar }
+	jarOption := func() (goya.BeforeBuildFunc, goya.AfterBuildFunc, goya.ClientBuildFunc, goya.ClientDoneFunc) {
+		return nil, nil, withJar, nil
+	}
+	cookies := goya.Get[map[string]map[string]string](server.URL+"/cookies/set", goya.NewOption(jarOption, goya.WithParams(map[string]string{"k": "v"})))
+	if cookies["cookies"]["k"] != "v" {
+		t.Errorf("cookies got %v but want %v", cookies, map[string]string{"k": "v"})
+	}
+
+	auth := goya.Get[map[string]any](server.URL+"/basic-auth/user/passwd", goya.NewOption(goya.WithForceHeader("Authorization", "Basic dXNlcjpwYXNzd2Q=")))
+	if auth["authenticated"] != true || auth["user"] != "user" {
+		t.Errorf("basic-auth got %v", auth)
+	}
+	resp = goya.RequestRaw(http.MethodGet, server.URL+"/basic-auth/user/passwd", goya.NewOption())
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("basic-auth StatusCode got %v but want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp = goya.RequestRaw(http.MethodGet, server.URL+"/stream/3", goya.NewOption())
+	lines := 0
+	scanner := bufio.NewScanner(resp.RawResponse.Body)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			lines++
+		}
+	}
+	if lines != 3 {
+		t.Errorf("stream got %v lines but want %v", lines, 3)
+	}
+
+	gzipped := goya.Get[map[string]any](server.URL+"/gzip", nil)
+	if gzipped["gzipped"] != true {
+		t.Errorf("gzip got %v", gzipped)
+	}
+}
@@ -0,0 +1,380 @@
+// Package goyatest provides helpers to test code built on goya without touching the network
+//
+// It only depends on the standard library, the helpers are injected with goya.WithTransport
+package goyatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TestingT is the part of *testing.T used by the assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Responder builds the response of a matched request
+type Responder func(req *http.Request) (*http.Response, error)
+
+// Mock is an http.RoundTripper that answers the requests with the registered expectations
+//
+//	mock := goyatest.NewMock()
+//	mock.Expect(http.MethodGet, "/users").WithQuery("page", "1").ReplyJSON(http.StatusOK, users)
+//	goya.Get[[]User](URL, goya.NewOption(goya.WithTransport(mock), goya.WithParams(params)))
+//	mock.AssertExpectations(t)
+type Mock struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []*http.Request
+	unmatched    []*http.Request
+}
+
+func NewMock() *Mock {
+	return &Mock{
+		expectations: []*Expectation{},
+		calls:        []*http.Request{},
+		unmatched:    []*http.Request{},
+	}
+}
+
+// Expect registers an expectation on the method and the path of the URL
+// An empty method or path matches any value
+// The expectations are checked in the order they were registered
+func (m *Mock) Expect(method, path string) *Expectation {
+	e := &Expectation{
+		mu:       &m.mu,
+		method:   method,
+		path:     path,
+		matchers: []func(req *http.Request, body []byte) bool{},
+		status:   http.StatusOK,
+		header:   http.Header{},
+	}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// RoundTrip answers req with the first expectation that matches it
+// If nothing matches, it returns an error and the request is recorded as unmatched
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, req)
+	var matched *Expectation
+	for _, e := range m.expectations {
+		if e.exhausted() || !e.match(req, body) {
+			continue
+		}
+		e.calls++
+		matched = e
+		break
+	}
+	if matched == nil {
+		m.unmatched = append(m.unmatched, req)
+	}
+	m.mu.Unlock()
+
+	if matched == nil {
+		return nil, fmt.Errorf("goyatest: no expectation matches %v %v", req.Method, req.URL)
+	}
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return matched.respond(req)
+}
+
+// Calls returns all requests received by the Mock
+func (m *Mock) Calls() []*http.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*http.Request{}, m.calls...)
+}
+
+// Unmatched returns the requests that matched no expectation
+func (m *Mock) Unmatched() []*http.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*http.Request{}, m.unmatched...)
+}
+
+// AssertExpectations reports every expectation that was not met and every unmatched request to t
+// It returns true if everything was met
+func (m *Mock) AssertExpectations(t TestingT) bool {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ok := true
+	for _, e := range m.expectations {
+		if e.times == 0 && e.calls == 0 {
+			t.Errorf("goyatest: %v was never called", e)
+			ok = false
+		}
+		if e.times > 0 && e.calls != e.times {
+			t.Errorf("goyatest: %v got %v calls but want %v", e, e.calls, e.times)
+			ok = false
+		}
+	}
+	for _, req := range m.unmatched {
+		t.Errorf("goyatest: unexpected request %v %v", req.Method, req.URL)
+		ok = false
+	}
+	return ok
+}
+
+// Reset removes all expectations and recorded requests
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = []*Expectation{}
+	m.calls = []*http.Request{}
+	m.unmatched = []*http.Request{}
+}
+
+// Expectation describes the requests it matches and the response it replies with
+// The methods return the Expectation itself so they can be chained
+type Expectation struct {
+	mu       *sync.Mutex
+	method   string
+	path     string
+	matchers []func(req *http.Request, body []byte) bool
+
+	times int
+	calls int
+
+	status    int
+	header    http.Header
+	body      []byte
+	responder Responder
+	err       error
+}
+
+// WithQuery matches the requests that have the query param with the value
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	e.matchers = append(e.matchers, func(req *http.Request, body []byte) bool {
+		for _, v := range req.URL.Query()[key] {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+	return e
+}
+
+// WithHeader matches the requests that have the header with the value
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.matchers = append(e.matchers, func(req *http.Request, body []byte) bool {
+		for _, v := range req.Header.Values(key) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+	return e
+}
+
+// WithJSON matches the requests whose body is the same JSON as data
+// The order of the keys and the spaces are ignored
+func (e *Expectation) WithJSON(data any) *Expectation {
+	want, err := normalizeJSON(data)
+	e.matchers = append(e.matchers, func(req *http.Request, body []byte) bool {
+		if err != nil {
+			return false
+		}
+		var got any
+		if json.Unmarshal(body, &got) != nil {
+			return false
+		}
+		return reflect.DeepEqual(got, want)
+	})
+	return e
+}
+
+// WithBody matches the requests whose body is exactly body
+func (e *Expectation) WithBody(body string) *Expectation {
+	e.matchers = append(e.matchers, func(req *http.Request, got []byte) bool {
+		return string(got) == body
+	})
+	return e
+}
+
+// Match matches the requests that f returns true for
+// The body of req can still be read by f
+func (e *Expectation) Match(f func(req *http.Request) bool) *Expectation {
+	e.matchers = append(e.matchers, func(req *http.Request, body []byte) bool {
+		return f(req)
+	})
+	return e
+}
+
+// Times sets how many times the Expectation must be called
+// It stops matching after n calls, by default it matches any number of times but at least once
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Once is the same as Times(1)
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Reply sets the status code and the body of the response
+func (e *Expectation) Reply(status int, body string) *Expectation {
+	e.status = status
+	e.body = []byte(body)
+	return e
+}
+
+// ReplyJSON sets the status code and the body of the response to data in JSON format
+// and sets the Content-Type to application/json
+func (e *Expectation) ReplyJSON(status int, data any) *Expectation {
+	bts, err := json.Marshal(data)
+	if err != nil {
+		e.err = err
+	}
+	e.status = status
+	e.body = bts
+	e.header.Set("Content-Type", "application/json")
+	return e
+}
+
+// ReplyHeader adds the header to the response
+func (e *Expectation) ReplyHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// ReplyError makes the request fail with err, like a network error
+func (e *Expectation) ReplyError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Respond sets a Responder that builds the response, it overrides the Reply* methods
+func (e *Expectation) Respond(responder Responder) *Expectation {
+	e.responder = responder
+	return e
+}
+
+// Calls returns how many times the Expectation has been matched
+func (e *Expectation) Calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+func (e *Expectation) String() string {
+	method, path := e.method, e.path
+	if method == "" {
+		method = "*"
+	}
+	if path == "" {
+		path = "*"
+	}
+	return fmt.Sprintf("expectation %v %v", method, path)
+}
+
+func (e *Expectation) exhausted() bool {
+	return e.times > 0 && e.calls >= e.times
+}
+
+func (e *Expectation) match(req *http.Request, body []byte) bool {
+	if e.method != "" && !strings.EqualFold(e.method, req.Method) {
+		return false
+	}
+	if e.path != "" && e.path != req.URL.Path {
+		return false
+	}
+	for _, m := range e.matchers {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if !m(req, body) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) respond(req *http.Request) (*http.Response, error) {
+	if e.responder != nil {
+		return e.responder(req)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return NewResponse(req, e.status, e.header.Clone(), e.body), nil
+}
+
+// NewResponse builds an *http.Response for req, it is useful to write a Responder
+func NewResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// readBody reads the body of req and returns a clone of req whose body can be read again
+// A RoundTripper must not change the request, so req keeps its body, which is closed as RoundTrip should
+func readBody(req *http.Request) (*http.Request, []byte, error) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil, nil
+	}
+	defer req.Body.Close()
+	var body []byte
+	var err error
+	if req.GetBody != nil {
+		var rc io.ReadCloser
+		if rc, err = req.GetBody(); err == nil {
+			body, err = io.ReadAll(rc)
+			rc.Close()
+		}
+	} else {
+		body, err = io.ReadAll(req.Body)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return clone, body, nil
+}
+
+// normalizeJSON converts data to the value json.Unmarshal would produce, so that it can be compared with reflect.DeepEqual
+func normalizeJSON(data any) (any, error) {
+	bts, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var result any
+	err = json.Unmarshal(bts, &result)
+	return result, err
+}
@@ -0,0 +1,122 @@
+package goyatest_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sagayosa/goya"
+	"github.com/sagayosa/goya/goyatest"
+)
+
+type user struct {
+	Name string `json:"name"`
+	ID   int    `json:"id"`
+}
+
+// recorder collects the errors reported by AssertExpectations
+type recorder struct {
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestMock(t *testing.T) {
+	mock := goyatest.NewMock()
+	mock.Expect(http.MethodGet, "/users").WithQuery("page", "1").WithHeader("Test-Header", "mock").
+		ReplyJSON(http.StatusOK, []user{{"Hello", 3306}})
+	mock.Expect(http.MethodPost, "/users").WithJSON(map[string]any{"id": 1, "name": "goya"}).Once().
+		Reply(http.StatusCreated, `{"name":"goya","id":1}`)
+	mock.Expect(http.MethodPost, "/echo").Respond(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		return goyatest.NewResponse(req, http.StatusOK, nil, body), nil
+	})
+
+	users := goya.Get[[]user]("http://example.com/users", goya.NewOption(
+		goya.WithTransport(mock),
+		goya.WithParams(map[string]string{"page": "1"}),
+		goya.WithForceHeader("Test-Header", "mock"),
+	))
+	if len(users) != 1 || users[0].Name != "Hello" {
+		t.Errorf("users got %v but want %v", users, []user{{"Hello", 3306}})
+	}
+
+	created := goya.Post[user]("http://example.com/users", goya.NewOption(goya.WithTransport(mock), goya.WithJson(user{"goya", 1})))
+	if created.Name != "goya" {
+		t.Errorf("created got %v but want %v", created, user{"goya", 1})
+	}
+
+	echo := goya.Post[user]("http://example.com/echo", goya.NewOption(goya.WithTransport(mock), goya.WithJson(user{"echo", 2})))
+	if echo.Name != "echo" || echo.ID != 2 {
+		t.Errorf("echo got %v but want %v", echo, user{"echo", 2})
+	}
+
+	if !mock.AssertExpectations(t) {
+		t.Error("AssertExpectations should be true")
+	}
+	if len(mock.Calls()) != 3 {
+		t.Errorf("Calls got %v but want %v", len(mock.Calls()), 3)
+	}
+}
+
+func TestMockUnmet(t *testing.T) {
+	mock := goyatest.NewMock()
+	users := mock.Expect(http.MethodGet, "/users").Times(2).ReplyJSON(http.StatusOK, []user{})
+	mock.Expect(http.MethodDelete, "").ReplyError(errors.New("connection reset"))
+	mock.Expect("", "/never")
+
+	errs := []error{}
+	goya.Get[[]user]("http://example.com/users", goya.NewOption(goya.WithTransport(mock)))
+	goya.Delete[any]("http://example.com/users/1", goya.NewOption(goya.WithTransport(mock), goya.WithError(&errs)))
+	if len(errs) == 0 {
+		t.Error("ReplyError should make the request fail")
+	}
+	goya.Get[any]("http://example.com/unknown", goya.NewOption(goya.WithTransport(mock)))
+
+	if users.Calls() != 1 {
+		t.Errorf("users.Calls got %v but want %v", users.Calls(), 1)
+	}
+	r := &recorder{}
+	if mock.AssertExpectations(r) {
+		t.Error("AssertExpectations should be false")
+	}
+	// /users called once, /never not called, /unknown unmatched
+	if len(r.errs) != 3 {
+		t.Errorf("AssertExpectations reported %v but want %v errors", r.errs, 3)
+	}
+	if len(mock.Unmatched()) != 1 {
+		t.Errorf("Unmatched got %v but want %v", len(mock.Unmatched()), 1)
+	}
+
+	mock.Reset()
+	if !mock.AssertExpectations(t) {
+		t.Error("AssertExpectations should be true after Reset")
+	}
+}
+
+func TestMockKeepsRequest(t *testing.T) {
+	mock := goyatest.NewMock()
+	mock.Expect(http.MethodPost, "/echo").Respond(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		return goyatest.NewResponse(req, http.StatusOK, nil, body), nil
+	})
+	req, _ := http.NewRequest(http.MethodPost, "http://goya.test/echo", strings.NewReader("goya"))
+	body := req.Body
+	resp, err := mock.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Body != body {
+		t.Errorf("the body of the request is replaced by RoundTrip")
+	}
+	if got, _ := io.ReadAll(resp.Body); string(got) != "goya" {
+		t.Errorf("body got %v but want %v", string(got), "goya")
+	}
+}
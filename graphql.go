@@ -0,0 +1,297 @@
+package goya
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// GraphQLOption changes the request of GraphQL
+type GraphQLOption func(c *graphQLConfig)
+
+type graphQLConfig struct {
+	operationName string
+	persisted     bool
+	uploads       []graphQLUpload
+}
+
+type graphQLUpload struct {
+	path     string
+	filename string
+	content  io.Reader
+}
+
+// GraphQLOperationName sets the operationName of the request, which is needed if the query has many operations
+func GraphQLOperationName(name string) GraphQLOption {
+	return func(c *graphQLConfig) {
+		c.operationName = name
+	}
+}
+
+// GraphQLPersistedQuery sends the SHA-256 of the query instead of the query, as the automatic persisted queries of Apollo
+// The query is sent again with its hash if the server does not know it
+func GraphQLPersistedQuery() GraphQLOption {
+	return func(c *graphQLConfig) {
+		c.persisted = true
+	}
+}
+
+// GraphQLUpload sends the request in multipart format, with the file as the value at path,
+// such as variables.file or variables.files.0, see https://github.com/jaydenseric/graphql-multipart-request-spec
+func GraphQLUpload(path, filename string, content io.Reader) GraphQLOption {
+	return func(c *graphQLConfig) {
+		c.uploads = append(c.uploads, graphQLUpload{path: path, filename: filename, content: content})
+	}
+}
+
+// GraphQLLocation is the position of an error in the query
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLError is an error of the errors array of a GraphQL response
+type GraphQLError struct {
+	Message    string            `json:"message"`
+	Locations  []GraphQLLocation `json:"locations,omitempty"`
+	Path       []any             `json:"path,omitempty"`
+	Extensions map[string]any    `json:"extensions,omitempty"`
+}
+
+func (e *GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	path := make([]string, 0, len(e.Path))
+	for _, p := range e.Path {
+		path = append(path, fmt.Sprint(p))
+	}
+	return fmt.Sprintf("%v : %v", strings.Join(path, "."), e.Message)
+}
+
+// GraphQLErrors is returned by GraphQL if the response has errors, the data may be partial then
+type GraphQLErrors []*GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return "graphql : " + strings.Join(messages, "; ")
+}
+
+// GraphQL sends the query and variables in the standard {query, variables, operationName} JSON envelope by POST
+// and decodes the data of the response into T. If the response has errors they are returned as GraphQLErrors
+// with the data decoded so far
+func GraphQL[T any](URL, query string, variables any, opt *Option, opts ...GraphQLOption) (T, error) {
+	config := graphQLConfig{}
+	for _, f := range opts {
+		f(&config)
+	}
+	envelope := map[string]any{"query": query}
+	if variables != nil {
+		envelope["variables"] = variables
+	}
+	if config.operationName != "" {
+		envelope["operationName"] = config.operationName
+	}
+
+	var result T
+	var body func() (OptionFunc, error)
+	if len(config.uploads) > 0 {
+		files, err := readUploads(config.uploads)
+		if err != nil {
+			return result, err
+		}
+		body = func() (OptionFunc, error) { return graphQLMultipart(envelope, config.uploads, files) }
+	} else {
+		body = func() (OptionFunc, error) { return WithJson(envelope), nil }
+	}
+
+	if config.persisted {
+		sum := sha256.Sum256([]byte(query))
+		envelope["extensions"] = map[string]any{
+			"persistedQuery": map[string]any{"version": 1, "sha256Hash": hex.EncodeToString(sum[:])},
+		}
+		delete(envelope, "query")
+		data, errs, err := sendGraphQL(URL, opt, body)
+		if err != nil || !persistedQueryNotFound(errs) {
+			return decodeGraphQL[T](data, errs, err)
+		}
+		envelope["query"] = query
+	}
+	data, errs, err := sendGraphQL(URL, opt, body)
+	return decodeGraphQL[T](data, errs, err)
+}
+
+func sendGraphQL(URL string, opt *Option, body func() (OptionFunc, error)) (json.RawMessage, GraphQLErrors, error) {
+	withBody, err := body()
+	if err != nil {
+		return nil, nil, err
+	}
+	client := NewRequestClient(http.MethodPost, URL, opt.with(withBody, withGraphQLAccept), nil)
+	resp := client.Do()
+	if errs := client.Errors(); errs != nil {
+		return nil, nil, errs[0]
+	}
+	bts, err := resp.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	result := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors GraphQLErrors   `json:"errors"`
+	}{}
+	// A GraphQL server may answer errors with a 4xx status, so the body is decoded first
+	if err := json.Unmarshal(bts, &result); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, nil, fmt.Errorf("graphql : unexpected status code %d", resp.StatusCode)
+		}
+		return nil, nil, fmt.Errorf("graphql : %w", err)
+	}
+	if len(result.Errors) == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return nil, nil, fmt.Errorf("graphql : unexpected status code %d", resp.StatusCode)
+	}
+	return result.Data, result.Errors, nil
+}
+
+// withGraphQLAccept asks for JSON unless the options set another Accept
+func withGraphQLAccept() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+	return nil, func(req *http.Request) {
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", contentTypeJSON)
+		}
+	}, nil, nil
+}
+
+func decodeGraphQL[T any](data json.RawMessage, errs GraphQLErrors, err error) (T, error) {
+	var result T
+	if err != nil {
+		return result, err
+	}
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, &result); err != nil {
+			return result, fmt.Errorf("graphql : %w", err)
+		}
+	}
+	if len(errs) > 0 {
+		return result, errs
+	}
+	return result, nil
+}
+
+func persistedQueryNotFound(errs GraphQLErrors) bool {
+	for _, err := range errs {
+		if err.Message == "PersistedQueryNotFound" || err.Extensions["code"] == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
+
+// readUploads reads the files at once, so they can be sent again for a persisted query
+func readUploads(uploads []graphQLUpload) ([][]byte, error) {
+	files := make([][]byte, 0, len(uploads))
+	for _, u := range uploads {
+		bts, err := io.ReadAll(u.content)
+		if err != nil {
+			return nil, fmt.Errorf("graphql upload %v : %w", u.path, err)
+		}
+		files = append(files, bts)
+	}
+	return files, nil
+}
+
+// graphQLMultipart builds the body of the multipart request spec, the files are null in the operations
+// and the map tells the server where to put each of them
+func graphQLMultipart(envelope map[string]any, uploads []graphQLUpload, files [][]byte) (OptionFunc, error) {
+	operations, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range uploads {
+		if operations, err = setJSONNull(operations, u.path); err != nil {
+			return nil, fmt.Errorf("graphql upload %v : %w", u.path, err)
+		}
+	}
+	fileMap := map[string][]string{}
+	for i, u := range uploads {
+		fileMap[strconv.Itoa(i)] = []string{u.path}
+	}
+	mapping, err := json.Marshal(fileMap)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("operations", string(operations)); err != nil {
+		return nil, err
+	}
+	if err := writer.WriteField("map", string(mapping)); err != nil {
+		return nil, err
+	}
+	for i, u := range uploads {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%d"; filename="%s"`, i, strings.ReplaceAll(u.filename, `"`, `\"`)))
+		header.Set(contentType, "application/octet-stream")
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(files[i]); err != nil {
+			return nil, fmt.Errorf("graphql upload %v : %w", u.path, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return withRawBody(body.Bytes(), writer.FormDataContentType()), nil
+}
+
+// setJSONNull sets the value at the dot separated path of the JSON document to null,
+// the objects and the arrays on the path must exist
+func setJSONNull(document []byte, path string) ([]byte, error) {
+	var root any
+	if err := json.Unmarshal(document, &root); err != nil {
+		return nil, err
+	}
+	keys := strings.Split(path, ".")
+	current := root
+	for i, key := range keys {
+		last := i == len(keys)-1
+		switch node := current.(type) {
+		case map[string]any:
+			if last {
+				node[key] = nil
+				continue
+			}
+			next, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("%v is not found", strings.Join(keys[:i+1], "."))
+			}
+			current = next
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("%v is not found", strings.Join(keys[:i+1], "."))
+			}
+			if last {
+				node[index] = nil
+				continue
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%v is not an object or an array", strings.Join(keys[:i], "."))
+		}
+	}
+	return json.Marshal(root)
+}
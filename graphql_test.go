@@ -0,0 +1,138 @@
+package goya
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+	Extensions    map[string]any `json:"extensions"`
+}
+
+func TestGraphQL(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := graphQLRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set(contentType, contentTypeJSON)
+		switch req.OperationName {
+		case "Hero":
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{"hero": map[string]any{"name": req.Variables["name"]}},
+			})
+		case "Partial":
+			w.Write([]byte(`{"data":{"hero":null},"errors":[{"message":"not found","locations":[{"line":1,"column":3}],"path":["hero",0],"extensions":{"code":"NOT_FOUND"}}]}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("bad gateway"))
+		}
+	})
+	type hero struct {
+		Hero *struct {
+			Name string `json:"name"`
+		} `json:"hero"`
+	}
+	opt := NewOption(WithHandler(handler))
+
+	got, err := GraphQL[hero]("http://goya.test/graphql", "query Hero($name: String) { hero { name } }", map[string]any{"name": "goya"}, opt, GraphQLOperationName("Hero"))
+	if err != nil || got.Hero == nil || got.Hero.Name != "goya" {
+		t.Errorf("GraphQL got %v, %v but want %v", got.Hero, err, "goya")
+	}
+
+	got, err = GraphQL[hero]("http://goya.test/graphql", "query Partial { hero { name } }", nil, opt, GraphQLOperationName("Partial"))
+	errs := GraphQLErrors{}
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("GraphQL errors got %v but want %v", err, "not found")
+	}
+	if e := errs[0]; e.Message != "not found" || len(e.Locations) != 1 || e.Locations[0] != (GraphQLLocation{1, 3}) ||
+		len(e.Path) != 2 || e.Extensions["code"] != "NOT_FOUND" {
+		t.Errorf("GraphQL error got %+v", e)
+	}
+	if got.Hero != nil {
+		t.Errorf("GraphQL partial data got %v but want %v", got.Hero, nil)
+	}
+	if err.Error() != "graphql : hero.0 : not found" {
+		t.Errorf("GraphQL error message got %v but want %v", err.Error(), "graphql : hero.0 : not found")
+	}
+
+	_, err = GraphQL[hero]("http://goya.test/graphql", "{ hero { name } }", nil, opt)
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("GraphQL with a bad status got %v but want %v", err, "status code 502")
+	}
+}
+
+func TestGraphQLPersistedQuery(t *testing.T) {
+	query := "{ hero { name } }"
+	known := map[string]bool{}
+	requests := int32(0)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		req := graphQLRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		hash, _ := req.Extensions["persistedQuery"].(map[string]any)["sha256Hash"].(string)
+		if req.Query == "" && !known[hash] {
+			w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`))
+			return
+		}
+		known[hash] = true
+		w.Write([]byte(`{"data":{"name":"goya"}}`))
+	})
+	opt := NewOption(WithHandler(handler))
+	for i, want := range []int32{2, 3} {
+		got, err := GraphQL[map[string]string]("http://goya.test/graphql", query, nil, opt, GraphQLPersistedQuery())
+		if err != nil || got["name"] != "goya" {
+			t.Errorf("GraphQL persisted query %d got %v, %v but want %v", i, got, err, "goya")
+		}
+		if n := atomic.LoadInt32(&requests); n != want {
+			t.Errorf("requests after persisted query %d got %v but want %v", i, n, want)
+		}
+	}
+}
+
+func TestGraphQLUpload(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		file, header, err := r.FormFile("0")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{
+			"operations": r.FormValue("operations"),
+			"map":        r.FormValue("map"),
+			"file":       header.Filename + ":" + string(content),
+		}})
+	})
+	got, err := GraphQL[map[string]string]("http://goya.test/graphql", "mutation ($file: Upload!) { upload(file: $file) }",
+		map[string]any{"file": nil}, NewOption(WithHandler(handler)), GraphQLUpload("variables.file", "a.txt", strings.NewReader("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"operations": `{"query":"mutation ($file: Upload!) { upload(file: $file) }","variables":{"file":null}}`,
+		"map":        `{"0":["variables.file"]}`,
+		"file":       "a.txt:hello",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%v got %v but want %v", k, got[k], v)
+		}
+	}
+
+	_, err = GraphQL[map[string]string]("http://goya.test/graphql", "{ a }", map[string]any{},
+		NewOption(WithHandler(handler)), GraphQLUpload("variables.files.0", "a.txt", strings.NewReader("hello")))
+	if err == nil {
+		t.Errorf("GraphQLUpload with a missing path got no error")
+	}
+}
@@ -0,0 +1,181 @@
+package goya
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// handlerRemoteAddr is the RemoteAddr seen by the handler, the same as httptest.NewRequest
+const handlerRemoteAddr = "192.0.2.1:1234"
+
+// WithHandler will send the requests to the handler in-process instead of through the network
+// The client is still used as usual, so redirects, cookies of the Jar and streaming responses behave like a real server
+func WithHandler(handler http.Handler) OptionFunc {
+	return WithTransport(&handlerTransport{handler: handler})
+}
+
+// handlerTransport is an http.RoundTripper that calls the handler in a goroutine
+// The response is returned as soon as the handler writes the header, and the body is streamed through a pipe
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	serverReq := req.Clone(ctx)
+	serverReq.RequestURI = req.URL.RequestURI()
+	serverReq.RemoteAddr = handlerRemoteAddr
+	serverReq.Proto, serverReq.ProtoMajor, serverReq.ProtoMinor = "HTTP/1.1", 1, 1
+	if serverReq.Host == "" {
+		serverReq.Host = req.URL.Host
+	}
+	if serverReq.Body == nil {
+		serverReq.Body = http.NoBody
+	}
+	if serverReq.ContentLength > 0 && serverReq.Header.Get("Content-Length") == "" {
+		serverReq.Header.Set("Content-Length", strconv.FormatInt(serverReq.ContentLength, 10))
+	}
+
+	pr, pw := io.Pipe()
+	w := &handlerWriter{
+		header: http.Header{},
+		method: req.Method,
+		pw:     pw,
+		ready:  make(chan struct{}),
+	}
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				err := fmt.Errorf("handler panic : %v", p)
+				w.fail(err)
+				pw.CloseWithError(err)
+				return
+			}
+			w.finish()
+			pw.Close()
+		}()
+		t.handler.ServeHTTP(w, serverReq)
+	}()
+
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		cancel()
+		pr.CloseWithError(ctx.Err())
+		return nil, ctx.Err()
+	}
+	if w.err != nil {
+		cancel()
+		return nil, w.err
+	}
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.snapshot,
+		ContentLength: -1,
+		Body:          &handlerBody{PipeReader: pr, cancel: cancel},
+		Request:       req,
+	}
+	if length, err := strconv.ParseInt(w.snapshot.Get("Content-Length"), 10, 64); err == nil {
+		resp.ContentLength = length
+	}
+	if req.Method == http.MethodHead {
+		resp.Body = http.NoBody
+		pr.Close()
+		cancel()
+	}
+	return resp, nil
+}
+
+// handlerBody cancels the context of the handler when the client closes the body
+type handlerBody struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (b *handlerBody) Close() error {
+	b.cancel()
+	return b.PipeReader.Close()
+}
+
+// handlerWriter is the http.ResponseWriter given to the handler
+type handlerWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	snapshot http.Header
+	status   int
+	method   string
+	pw       *io.PipeWriter
+	ready    chan struct{}
+	err      error
+}
+
+func (w *handlerWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *handlerWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeader(status, nil)
+}
+
+func (w *handlerWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	w.writeHeader(http.StatusOK, data)
+	w.mu.Unlock()
+	if w.method == http.MethodHead {
+		return len(data), nil
+	}
+	return w.pw.Write(data)
+}
+
+// Flush sends the header to the client, the written data is already unbuffered
+func (w *handlerWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeader(http.StatusOK, nil)
+}
+
+// writeHeader must be called with w.mu held, it only takes effect once
+// The Content-Type is detected from the first data like net/http does
+func (w *handlerWriter) writeHeader(status int, data []byte) {
+	if w.snapshot != nil {
+		return
+	}
+	if len(data) > 0 && w.header.Get("Content-Type") == "" && w.header.Get("Content-Encoding") == "" {
+		w.header.Set("Content-Type", http.DetectContentType(data))
+	}
+	w.status = status
+	w.snapshot = w.header.Clone()
+	close(w.ready)
+}
+
+// finish sends the header if the handler returned without writing anything
+func (w *handlerWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.snapshot == nil && w.header.Get("Content-Length") == "" {
+		w.header.Set("Content-Length", "0")
+	}
+	w.writeHeader(http.StatusOK, nil)
+}
+
+func (w *handlerWriter) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.snapshot != nil {
+		return
+	}
+	w.err = err
+	w.snapshot = http.Header{}
+	close(w.ready)
+}
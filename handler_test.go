@@ -0,0 +1,72 @@
+package goya
+
+import (
+	"bufio"
+	"net/http"
+	"testing"
+
+	"github.com/sagayosa/goya/goyatest"
+)
+
+func TestWithHandler(t *testing.T) {
+	handler := WithHandler(goyatest.NewHTTPBinHandler())
+
+	req := testStruct{"Hello", 3306}
+	resp := Post[BasicPostResponse]("http://goya.test/post", NewOption(handler, WithParams(map[string]string{"temp": "2"}), WithJson(req),
+		WithForceHeader("Test-Header", "handler"), WithCookies([]*http.Cookie{{Name: "Test", Value: "123"}})))
+	if resp.URL != "http://goya.test/post?temp=2" {
+		t.Errorf("resp.URL got %v but want %v", resp.URL, "http://goya.test/post?temp=2")
+	}
+	if resp.Data != `{"name":"Hello","id":3306}` {
+		t.Errorf("resp.Data got %v but want %v", resp.Data, `{"name":"Hello","id":3306}`)
+	}
+	if resp.Headers.TestHeader != "handler" || resp.Headers.Cookie != "Test=123" {
+		t.Errorf("resp.Headers got %v", resp.Headers)
+	}
+
+	redirected := Get[BasicGetResponse]("http://goya.test/redirect/2", NewOption(handler))
+	if redirected.URL != "http://goya.test/get" {
+		t.Errorf("redirected.URL got %v but want %v", redirected.URL, "http://goya.test/get")
+	}
+
+	status := RequestRaw(http.MethodGet, "http://goya.test/status/404", NewOption(handler))
+	if status.StatusCode != http.StatusNotFound {
+		t.Errorf("StatusCode got %v but want %v", status.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestWithHandlerStreaming(t *testing.T) {
+	next := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		// The client gets the first line before the handler writes the second one
+		<-next
+		w.Write([]byte("second\n"))
+	})
+
+	resp := RequestRaw(http.MethodGet, "http://goya.test/stream", NewOption(WithHandler(handler)))
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/plain" {
+		t.Fatalf("resp got %v %v", resp.StatusCode, resp.Header)
+	}
+	reader := bufio.NewReader(resp.RawResponse.Body)
+	line, _ := reader.ReadString('\n')
+	if line != "first\n" {
+		t.Errorf("first line got %q", line)
+	}
+	close(next)
+	line, _ = reader.ReadString('\n')
+	if line != "second\n" {
+		t.Errorf("second line got %q", line)
+	}
+	resp.RawResponse.Body.Close()
+
+	panicked := []error{}
+	RequestRaw(http.MethodGet, "http://goya.test/", NewOption(WithError(&panicked), WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))))
+	if len(panicked) == 0 {
+		t.Error("a panic in the handler should be an error")
+	}
+}
@@ -0,0 +1,447 @@
+package goya
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR is an HTTP Archive 1.2, see http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the total time of the exchange in milliseconds
+	Time     float64     `json:"time"`
+	Request  HARRequest  `json:"request"`
+	Response HARResponse `json:"response"`
+	Cache    struct{}    `json:"cache"`
+	Timings  HARTimings  `json:"timings"`
+	Comment  string      `json:"comment,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string     `json:"mimeType"`
+	Params   []HARParam `json:"params"`
+	Text     string     `json:"text"`
+}
+
+type HARParam struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	FileName    string `json:"fileName,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	// Encoding is base64 if Text is encoded because the content is binary
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings are in milliseconds, -1 means the phase is unknown
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARRecorder captures the exchanges of the requests using WithHAR
+// It can be shared by many requests and is safe for concurrent use
+type HARRecorder struct {
+	NopObserver
+	mu      sync.Mutex
+	entries []*HAREntry
+}
+
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{entries: []*HAREntry{}}
+}
+
+// WithHAR will capture every exchange of the request into the recorder, including the redirects and the retries
+// The content of a response is captured while its body is read
+func WithHAR(recorder *HARRecorder) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		if recorder == nil {
+			return func(b *RequestBuider) { b.ErrHappen(fmt.Errorf("WithHAR recorder is nil")) }, nil, nil, nil
+		}
+		before, _, _, _ := WithObserver(recorder)()
+		return before, func(req *http.Request) {
+			if req == nil {
+				return
+			}
+			t := &harTrace{}
+			ctx := context.WithValue(req.Context(), harTraceKey{}, t)
+			*req = *req.WithContext(httptrace.WithClientTrace(ctx, t.trace()))
+		}, nil, nil
+	}
+}
+
+// HAR returns a copy of the captured exchanges
+func (r *HARRecorder) HAR() *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]*HAREntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entry := *e
+		entries = append(entries, &entry)
+	}
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "goya", Version: "1.0"},
+		Entries: entries,
+	}}
+}
+
+// WriteTo writes the HAR in JSON format
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	bts, err := json.MarshalIndent(r.HAR(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(bts)
+	return int64(n), err
+}
+
+// WriteFile writes the HAR to the file at path, it can be loaded by the browser dev tools
+func (r *HARRecorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := r.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Reset removes the captured exchanges
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = []*HAREntry{}
+}
+
+func (r *HARRecorder) OnResponse(req *http.Request, resp *http.Response, elapsed time.Duration) {
+	entry := &HAREntry{
+		StartedDateTime: time.Now().Add(-elapsed),
+		Time:            milliseconds(elapsed),
+		Request:         harRequest(req),
+		Response:        harResponse(resp),
+		Timings:         HARTimings{Blocked: -1, DNS: -1, Connect: -1, Send: 0, Wait: milliseconds(elapsed), Receive: 0, SSL: -1},
+	}
+	if t, ok := req.Context().Value(harTraceKey{}).(*harTrace); ok {
+		entry.Timings = t.timings(entry.Timings)
+	}
+	if attempt, _ := attemptOf(req.Context()); attempt > 1 {
+		entry.Comment = fmt.Sprintf("attempt %d", attempt)
+	}
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	resp.Body = &harBody{ReadCloser: resp.Body, recorder: r, entry: entry, start: time.Now()}
+}
+
+func (r *HARRecorder) OnError(req *http.Request, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, &HAREntry{
+		StartedDateTime: time.Now(),
+		Request:         harRequest(req),
+		Response:        HARResponse{Cookies: []HARCookie{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1},
+		Timings:         HARTimings{Blocked: -1, DNS: -1, Connect: -1, Wait: -1, Receive: -1, SSL: -1},
+		Comment:         err.Error(),
+	})
+}
+
+type harTraceKey struct{}
+
+// harTrace records the phases of the current hop, the hops of a request are sent one after another
+type harTrace struct {
+	mu  sync.Mutex
+	hop harHop
+}
+
+type harHop struct {
+	getConn, dnsStart, dnsDone, connectStart time.Time
+	connectDone, tlsStart, tlsDone, gotConn  time.Time
+	wroteRequest, firstByte                  time.Time
+}
+
+func (t *harTrace) trace() *httptrace.ClientTrace {
+	now := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*field = time.Now()
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.hop = harHop{getConn: time.Now()}
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { now(&t.hop.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { now(&t.hop.dnsDone) },
+		ConnectStart:         func(string, string) { now(&t.hop.connectStart) },
+		ConnectDone:          func(string, string, error) { now(&t.hop.connectDone) },
+		TLSHandshakeStart:    func() { now(&t.hop.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { now(&t.hop.tlsDone) },
+		GotConn:              func(httptrace.GotConnInfo) { now(&t.hop.gotConn) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { now(&t.hop.wroteRequest) },
+		GotFirstResponseByte: func() { now(&t.hop.firstByte) },
+	}
+}
+
+// timings fills the phases that were traced, the other ones are kept from fallback
+func (t *harTrace) timings(fallback HARTimings) HARTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := func(start, end time.Time) float64 {
+		if start.IsZero() || end.IsZero() {
+			return -1
+		}
+		return milliseconds(end.Sub(start))
+	}
+	h := t.hop
+	result := fallback
+	if h.gotConn.IsZero() || h.firstByte.IsZero() {
+		return result
+	}
+	result.DNS = span(h.dnsStart, h.dnsDone)
+	result.Connect = span(h.connectStart, h.connectDone)
+	result.SSL = span(h.tlsStart, h.tlsDone)
+	// In HAR the connect time includes the ssl time
+	if result.Connect >= 0 && result.SSL >= 0 {
+		result.Connect += result.SSL
+	}
+	result.Blocked = span(h.getConn, h.gotConn)
+	if result.Connect >= 0 || result.DNS >= 0 {
+		result.Blocked -= max(result.DNS, 0) + max(result.Connect, 0)
+	}
+	result.Send = max(span(h.gotConn, h.wroteRequest), 0)
+	result.Wait = span(h.wroteRequest, h.firstByte)
+	if result.Wait < 0 {
+		result.Wait = span(h.gotConn, h.firstByte)
+	}
+	return result
+}
+
+// harBody captures the content of the response into the entry
+type harBody struct {
+	io.ReadCloser
+	recorder *HARRecorder
+	entry    *HAREntry
+	start    time.Time
+	content  bytes.Buffer
+	once     sync.Once
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.content.Write(p[:n])
+	if err != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+func (b *harBody) done() {
+	b.once.Do(func() {
+		b.recorder.mu.Lock()
+		defer b.recorder.mu.Unlock()
+		content := b.content.Bytes()
+		receive := milliseconds(time.Since(b.start))
+		b.entry.Timings.Receive = receive
+		b.entry.Time += receive
+		b.entry.Response.BodySize = int64(len(content))
+		b.entry.Response.Content.Size = int64(len(content))
+		if utf8.Valid(content) {
+			b.entry.Response.Content.Text = string(content)
+		} else {
+			b.entry.Response.Content.Text = base64.StdEncoding.EncodeToString(content)
+			b.entry.Response.Content.Encoding = "base64"
+		}
+	})
+}
+
+func harRequest(req *http.Request) HARRequest {
+	result := HARRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: harHTTPVersion(req.Proto),
+		Cookies:     []HARCookie{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	for _, c := range req.Cookies() {
+		result.Cookies = append(result.Cookies, HARCookie{Name: c.Name, Value: c.Value})
+	}
+	for k, values := range req.URL.Query() {
+		for _, v := range values {
+			result.QueryString = append(result.QueryString, HARNameValue{Name: k, Value: v})
+		}
+	}
+	body, err := requestBody(req)
+	if err != nil || len(body) == 0 {
+		return result
+	}
+	result.BodySize = int64(len(body))
+	result.PostData = harPostData(body, req.Header.Get(contentType))
+	return result
+}
+
+func harPostData(body []byte, contentTypeValue string) *HARPostData {
+	postData := &HARPostData{MimeType: contentTypeValue, Params: []HARParam{}}
+	mediaType, params, _ := mime.ParseMediaType(contentTypeValue)
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, _ := url.ParseQuery(string(body))
+		for k, vs := range values {
+			for _, v := range vs {
+				postData.Params = append(postData.Params, HARParam{Name: k, Value: v})
+			}
+		}
+	case "multipart/form-data":
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			value, _ := io.ReadAll(part)
+			param := HARParam{Name: part.FormName(), FileName: part.FileName(), ContentType: part.Header.Get(contentType)}
+			if utf8.Valid(value) {
+				param.Value = string(value)
+			}
+			postData.Params = append(postData.Params, param)
+		}
+	}
+	if utf8.Valid(body) {
+		postData.Text = string(body)
+	}
+	return postData
+}
+
+func harResponse(resp *http.Response) HARResponse {
+	result := HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: harHTTPVersion(resp.Proto),
+		Cookies:     []HARCookie{},
+		Headers:     harHeaders(resp.Header),
+		Content:     HARContent{Size: 0, MimeType: resp.Header.Get(contentType)},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	for _, c := range resp.Cookies() {
+		cookie := HARCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure}
+		if !c.Expires.IsZero() {
+			expires := c.Expires
+			cookie.Expires = &expires
+		}
+		result.Cookies = append(result.Cookies, cookie)
+	}
+	return result
+}
+
+func harHeaders(header http.Header) []HARNameValue {
+	result := []HARNameValue{}
+	for k, values := range header {
+		for _, v := range values {
+			result = append(result, HARNameValue{Name: k, Value: v})
+		}
+	}
+	return result
+}
+
+func harHTTPVersion(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,235 @@
+package goya
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// HARCall is a request of a HAR entry that can be sent again
+type HARCall struct {
+	Method string
+	URL    string
+	Opt    *Option
+	// Entry is the recorded exchange
+	Entry *HAREntry
+}
+
+// Do sends the request again, opts are applied after the recorded ones
+func (c *HARCall) Do(opts ...OptionFunc) *Response {
+	return RequestRaw(c.Method, c.URL, c.Opt.with(opts...))
+}
+
+// HAROption filters and rewrites the entries of a HAR when they are converted into calls
+type HAROption func(c *harConfig)
+
+type harConfig struct {
+	patterns []string
+	methods  []string
+	baseURL  string
+}
+
+// HARMatchURL keeps the entries whose URL matches any of the regular expressions
+func HARMatchURL(patterns ...string) HAROption {
+	return func(c *harConfig) {
+		c.patterns = append(c.patterns, patterns...)
+	}
+}
+
+// HARMethods keeps the entries with any of the methods
+func HARMethods(methods ...string) HAROption {
+	return func(c *harConfig) {
+		c.methods = append(c.methods, methods...)
+	}
+}
+
+// HARBaseURL sends the calls to baseURL instead of the recorded scheme and host
+// The path of baseURL is prefixed to the recorded path
+func HARBaseURL(baseURL string) HAROption {
+	return func(c *harConfig) {
+		c.baseURL = baseURL
+	}
+}
+
+// LoadHAR reads the HAR file at path and converts its entries into calls, in the recorded order
+func LoadHAR(path string, opts ...HAROption) ([]*HARCall, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	har := &HAR{}
+	if err := json.Unmarshal(bts, har); err != nil {
+		return nil, fmt.Errorf("LoadHAR %v : %w", path, err)
+	}
+	return har.Calls(opts...)
+}
+
+// ReplayHAR sends the calls one after another and returns their responses
+// The bodies are read before the next call is sent, opts are applied to every call
+func ReplayHAR(calls []*HARCall, opts ...OptionFunc) []*Response {
+	result := make([]*Response, 0, len(calls))
+	for _, call := range calls {
+		resp := call.Do(opts...)
+		resp.Bytes()
+		result = append(result, resp)
+	}
+	return result
+}
+
+// Calls converts the entries into calls, in the recorded order
+func (h *HAR) Calls(opts ...HAROption) ([]*HARCall, error) {
+	config := harConfig{}
+	for _, f := range opts {
+		f(&config)
+	}
+	patterns := make([]*regexp.Regexp, 0, len(config.patterns))
+	for _, p := range config.patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, re)
+	}
+	var base *url.URL
+	if config.baseURL != "" {
+		u, err := url.Parse(config.baseURL)
+		if err != nil {
+			return nil, err
+		}
+		base = u
+	}
+
+	calls := []*HARCall{}
+	for _, entry := range h.Log.Entries {
+		if !harMatch(entry, patterns, config.methods) {
+			continue
+		}
+		call, err := harCall(entry, base)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+func harMatch(entry *HAREntry, patterns []*regexp.Regexp, methods []string) bool {
+	if len(methods) > 0 {
+		matched := false
+		for _, m := range methods {
+			matched = matched || strings.EqualFold(m, entry.Request.Method)
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, re := range patterns {
+		if re.MatchString(entry.Request.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// harSkippedHeaders are set by net/http when the request is sent again
+var harSkippedHeaders = []string{"Host", "Content-Length", "Connection", "Accept-Encoding", "Transfer-Encoding"}
+
+func harCall(entry *HAREntry, base *url.URL) (*HARCall, error) {
+	u, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	if base != nil {
+		u.Scheme = base.Scheme
+		u.Host = base.Host
+		u.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+		u.RawPath = ""
+	}
+
+	header := http.Header{}
+	for _, h := range entry.Request.Headers {
+		// HTTP/2 pseudo headers such as :authority are recorded by the browsers
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		header.Add(h.Name, h.Value)
+	}
+	for _, k := range harSkippedHeaders {
+		header.Del(k)
+	}
+	opts := []OptionFunc{}
+	if postData := entry.Request.PostData; postData != nil {
+		body, mimeType := []byte(postData.Text), postData.MimeType
+		if len(body) == 0 && len(postData.Params) > 0 {
+			if body, mimeType, err = harParamsBody(postData); err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, withRawBody(body, mimeType))
+		header.Del(contentType)
+	}
+	opts = append(opts, WithForceHeaders(header))
+
+	return &HARCall{
+		Method: entry.Request.Method,
+		URL:    u.String(),
+		Opt:    NewOption(opts...),
+		Entry:  entry,
+	}, nil
+}
+
+// harParamsBody builds the body of the params recorded without the text,
+// as a multipart body with the boundary of mimeType if it is multipart/form-data, or url encoded otherwise
+func harParamsBody(postData *HARPostData) ([]byte, string, error) {
+	mediaType, params, _ := mime.ParseMediaType(postData.MimeType)
+	if mediaType != "multipart/form-data" {
+		values := url.Values{}
+		for _, p := range postData.Params {
+			values.Add(p.Name, p.Value)
+		}
+		return []byte(values.Encode()), postData.MimeType, nil
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if boundary := params["boundary"]; boundary != "" {
+		if err := writer.SetBoundary(boundary); err != nil {
+			return nil, "", fmt.Errorf("HAR multipart boundary : %w", err)
+		}
+	}
+	for _, p := range postData.Params {
+		var err error
+		if p.FileName == "" {
+			err = writer.WriteField(p.Name, p.Value)
+		} else {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, p.Name, p.FileName))
+			if p.ContentType != "" {
+				header.Set(contentType, p.ContentType)
+			}
+			var part io.Writer
+			if part, err = writer.CreatePart(header); err == nil {
+				_, err = io.WriteString(part, p.Value)
+			}
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("HAR multipart param %v : %w", p.Name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
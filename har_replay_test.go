@@ -0,0 +1,116 @@
+package goya
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sagayosa/goya/goyatest"
+)
+
+func TestLoadHAR(t *testing.T) {
+	recorder := NewHARRecorder()
+	handler := WithHandler(goyatest.NewHTTPBinHandler())
+	RequestRaw(http.MethodGet, "http://example.com/get?page=1", NewOption(handler, WithHAR(recorder), WithForceHeader("Test-Header", "goya"))).Bytes()
+	RequestRaw(http.MethodPost, "http://example.com/post", NewOption(handler, WithHAR(recorder), WithJson(map[string]string{"name": "goya"}))).Bytes()
+	RequestRaw(http.MethodPut, "http://example.com/put", NewOption(handler, WithHAR(recorder), WithForm(map[string]string{"name": "goya"}))).Bytes()
+	path := filepath.Join(t.TempDir(), "goya.har")
+	if err := recorder.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	calls, err := LoadHAR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("len(calls) got %v but want %v", len(calls), 3)
+	}
+	if calls[0].Method != http.MethodGet || calls[0].URL != "http://example.com/get?page=1" {
+		t.Errorf("calls[0] got %v %v", calls[0].Method, calls[0].URL)
+	}
+
+	calls, err = LoadHAR(path, HARMethods("post", "PUT"), HARMatchURL("/po", "/pu"), HARBaseURL("http://goya.test/anything/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0].URL != "http://goya.test/anything/post" {
+		t.Fatalf("filtered calls got %v", calls)
+	}
+	responses := ReplayHAR(calls, handler)
+	posted, put := BasicPostResponse{}, BasicPostResponse{}
+	if err := json.Unmarshal(responses[0].Body, &posted); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(responses[1].Body, &put); err != nil {
+		t.Fatal(err)
+	}
+	if posted.Data != `{"name":"goya"}` {
+		t.Errorf("posted.Data got %v but want %v", posted.Data, `{"name":"goya"}`)
+	}
+	if form, _ := put.Form.(map[string]any); form["name"] != "goya" {
+		t.Errorf("put.Form got %v", put.Form)
+	}
+
+	calls, _ = LoadHAR(path, HARMatchURL(`/get\?`))
+	bts, _ := calls[0].Do(handler).Bytes()
+	got := BasicGetResponse{}
+	if err := json.Unmarshal(bts, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Headers.TestHeader != "goya" || got.URL != "http://example.com/get?page=1" {
+		t.Errorf("replayed get got %v %v", got.Headers.TestHeader, got.URL)
+	}
+
+	if _, err := LoadHAR(path, HARMatchURL("(")); err == nil {
+		t.Errorf("LoadHAR with an invalid pattern got nil error")
+	}
+}
+
+func TestHARMultipartParams(t *testing.T) {
+	har := &HAR{Log: HARLog{Entries: []*HAREntry{{
+		Request: HARRequest{
+			Method: http.MethodPost,
+			URL:    "http://goya.test/upload",
+			PostData: &HARPostData{
+				MimeType: "multipart/form-data; boundary=goyaboundary",
+				Params: []HARParam{
+					{Name: "name", Value: "goya"},
+					{Name: "upload", Value: "file content", FileName: "a.txt", ContentType: "text/plain"},
+				},
+			},
+		},
+	}}}}
+	calls, err := har.Calls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got["contentType"] = r.Header.Get("Content-Type")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		got["name"] = r.FormValue("name")
+		file, header, err := r.FormFile("upload")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		got["upload"] = header.Filename + ":" + header.Header.Get("Content-Type") + ":" + string(content)
+	})
+	calls[0].Do(WithHandler(handler)).Bytes()
+	want := map[string]string{
+		"contentType": "multipart/form-data; boundary=goyaboundary",
+		"name":        "goya",
+		"upload":      "a.txt:text/plain:file content",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("multipart params got %v but want %v", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package goya
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagayosa/goya/goyatest"
+)
+
+func TestWithHAR(t *testing.T) {
+	recorder := NewHARRecorder()
+	resp := RequestRaw(http.MethodPost, "http://httpbin.org/redirect/1", NewOption(
+		WithHAR(recorder),
+		WithParams(map[string]string{"page": "1"}),
+		WithCookies([]*http.Cookie{{Name: "session", Value: "abc"}}),
+		WithForm(map[string]string{"name": "goya"}),
+	))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("resp.StatusCode got %v but want %v", resp.StatusCode, http.StatusOK)
+	}
+	resp.Bytes()
+
+	entries := recorder.HAR().Log.Entries
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) got %v but want %v", len(entries), 2)
+	}
+	redirect, last := entries[0], entries[1]
+	if redirect.Response.Status != http.StatusFound || redirect.Response.RedirectURL != "/get" {
+		t.Errorf("redirect response got %v %v", redirect.Response.Status, redirect.Response.RedirectURL)
+	}
+	if redirect.Request.Method != http.MethodPost || redirect.Request.PostData == nil {
+		t.Fatalf("redirect request got %v %v", redirect.Request.Method, redirect.Request.PostData)
+	}
+	if params := redirect.Request.PostData.Params; len(params) != 1 || params[0] != (HARParam{Name: "name", Value: "goya"}) {
+		t.Errorf("postData.params got %v", params)
+	}
+	if q := redirect.Request.QueryString; len(q) != 1 || q[0] != (HARNameValue{Name: "page", Value: "1"}) {
+		t.Errorf("queryString got %v", q)
+	}
+	if c := redirect.Request.Cookies; len(c) != 1 || c[0].Name != "session" || c[0].Value != "abc" {
+		t.Errorf("cookies got %v", c)
+	}
+	if redirect.Timings.Connect < 0 || redirect.Timings.Wait < 0 {
+		t.Errorf("timings got %+v", redirect.Timings)
+	}
+	if last.Request.URL != "http://httpbin.org/get" || last.Response.Status != http.StatusOK {
+		t.Errorf("last entry got %v %v", last.Request.URL, last.Response.Status)
+	}
+	if last.Response.Content.Size == 0 || last.Response.Content.Text == "" || last.Response.Content.MimeType != "application/json" {
+		t.Errorf("last content got %+v", last.Response.Content)
+	}
+
+	path := filepath.Join(t.TempDir(), "goya.har")
+	if err := recorder.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	har := &HAR{}
+	if err := json.Unmarshal(bts, har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 2 {
+		t.Errorf("har got %v %v", har.Log.Version, len(har.Log.Entries))
+	}
+}
+
+func TestWithHARBinaryAndError(t *testing.T) {
+	recorder := NewHARRecorder()
+	mock := goyatest.NewMock()
+	mock.Expect(http.MethodGet, "/bin").Reply(http.StatusOK, string([]byte{0xff, 0x00, 0xfe}))
+	RequestRaw(http.MethodGet, "http://goya.test/bin", NewOption(WithTransport(mock), WithHAR(recorder), withAttempt(2, nil))).Bytes()
+	RequestRaw(http.MethodGet, "http://goya.test/missing", NewOption(WithTransport(mock), WithHAR(recorder)))
+
+	entries := recorder.HAR().Log.Entries
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) got %v but want %v", len(entries), 2)
+	}
+	if c := entries[0].Response.Content; c.Encoding != "base64" || c.Text != "/wD+" || c.Size != 3 {
+		t.Errorf("content got %+v", c)
+	}
+	if entries[0].Comment != "attempt 2" {
+		t.Errorf("comment got %v but want %v", entries[0].Comment, "attempt 2")
+	}
+	if entries[1].Response.Status != 0 || entries[1].Comment == "" {
+		t.Errorf("error entry got %v %v", entries[1].Response.Status, entries[1].Comment)
+	}
+
+	recorder.Reset()
+	if n := len(recorder.HAR().Log.Entries); n != 0 {
+		t.Errorf("entries after Reset got %v but want %v", n, 0)
+	}
+}
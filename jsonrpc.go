@@ -0,0 +1,198 @@
+package goya
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// The error codes defined by JSON-RPC 2.0, the codes from -32000 to -32099 are for the errors of the server
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+)
+
+// ErrRPCNoResponse is the error of a call of a batch which the server did not answer
+var ErrRPCNoResponse = errors.New("jsonrpc : no response for the call")
+
+// RPCError is the error object of a JSON-RPC response
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	if len(e.Data) == 0 {
+		return fmt.Sprintf("jsonrpc : %d %v", e.Code, e.Message)
+	}
+	return fmt.Sprintf("jsonrpc : %d %v : %s", e.Code, e.Message, e.Data)
+}
+
+// rpcID is shared by all the calls, so the ids of a batch are unique
+var rpcID atomic.Uint64
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+	ID      any    `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// RPC calls method of the JSON-RPC 2.0 server at URL and decodes the result into T
+// params should be an array or an object if it is not nil, an error of the server is returned as an *RPCError
+func RPC[T any](URL, method string, params any, opt *Option) (T, error) {
+	batch := NewRPCBatch()
+	call := batch.Call(method, params)
+	var result T
+	if err := batch.send(URL, opt, false); err != nil {
+		return result, err
+	}
+	return RPCResult[T](call)
+}
+
+// RPCNotify sends a notification, which has no id and so no response
+func RPCNotify(URL, method string, params any, opt *Option) error {
+	batch := NewRPCBatch()
+	batch.Notify(method, params)
+	return batch.send(URL, opt, false)
+}
+
+// RPCBatch sends many calls and notifications in one request
+type RPCBatch struct {
+	requests []rpcRequest
+	calls    map[string]*RPCCall
+}
+
+// RPCCall is a call of a batch, its result is set after the batch is done
+type RPCCall struct {
+	Method string
+	// Result is the raw result of the call
+	Result json.RawMessage
+	// Err is an *RPCError if the server answered an error, or the error of sending the batch
+	Err error
+}
+
+// NewRPCBatch returns an empty batch
+func NewRPCBatch() *RPCBatch {
+	return &RPCBatch{calls: map[string]*RPCCall{}}
+}
+
+// Call adds a call of method to the batch
+func (b *RPCBatch) Call(method string, params any) *RPCCall {
+	id := rpcID.Add(1)
+	b.requests = append(b.requests, rpcRequest{JSONRPC: "2.0", Method: method, Params: params, ID: id})
+	call := &RPCCall{Method: method, Err: ErrRPCNoResponse}
+	b.calls[strconv.FormatUint(id, 10)] = call
+	return call
+}
+
+// Notify adds a notification of method to the batch
+func (b *RPCBatch) Notify(method string, params any) {
+	b.requests = append(b.requests, rpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// Do sends the batch as an array and matches the responses to the calls by id
+// The returned error is about the batch, the error of each call is in its Err
+func (b *RPCBatch) Do(URL string, opt *Option) error {
+	if len(b.requests) == 0 {
+		return fmt.Errorf("jsonrpc : the batch is empty")
+	}
+	return b.send(URL, opt, true)
+}
+
+// Decode decodes the result of the call into v, or returns the error of the call
+func (c *RPCCall) Decode(v any) error {
+	if c.Err != nil {
+		return c.Err
+	}
+	if err := json.Unmarshal(c.Result, v); err != nil {
+		return fmt.Errorf("jsonrpc %v : %w", c.Method, err)
+	}
+	return nil
+}
+
+// RPCResult decodes the result of the call into T
+func RPCResult[T any](call *RPCCall) (T, error) {
+	var result T
+	err := call.Decode(&result)
+	return result, err
+}
+
+func (b *RPCBatch) send(URL string, opt *Option, array bool) error {
+	var payload any = b.requests
+	if !array {
+		payload = b.requests[0]
+	}
+	client := NewRequestClient(http.MethodPost, URL, opt.with(WithJson(payload)), nil)
+	resp := client.Do()
+	if errs := client.Errors(); errs != nil {
+		return b.fail(errs[0])
+	}
+	bts, err := resp.Bytes()
+	if err != nil {
+		return b.fail(err)
+	}
+	bts = bytes.TrimSpace(bts)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// A server may answer an error object with an HTTP error status
+		single := rpcResponse{}
+		if json.Unmarshal(bts, &single) == nil && single.Error != nil {
+			return b.fail(single.Error)
+		}
+		return b.fail(fmt.Errorf("jsonrpc : unexpected status code %d", resp.StatusCode))
+	}
+	// Only notifications were sent
+	if len(b.calls) == 0 {
+		return nil
+	}
+
+	responses := []rpcResponse{}
+	if len(bts) > 0 && bts[0] == '[' {
+		err = json.Unmarshal(bts, &responses)
+	} else {
+		single := rpcResponse{}
+		err = json.Unmarshal(bts, &single)
+		responses = append(responses, single)
+	}
+	if err != nil {
+		return b.fail(fmt.Errorf("jsonrpc : %w", err))
+	}
+	for _, r := range responses {
+		call, ok := b.calls[string(bytes.Trim(r.ID, `"`))]
+		if !ok {
+			// An error with a null id is about the whole request, such as a parse error
+			if r.Error != nil && (len(r.ID) == 0 || string(r.ID) == "null") {
+				return b.fail(r.Error)
+			}
+			continue
+		}
+		if r.Error != nil {
+			call.Err = r.Error
+		} else {
+			call.Result, call.Err = r.Result, nil
+		}
+	}
+	return nil
+}
+
+// fail sets err to the calls and returns it
+func (b *RPCBatch) fail(err error) error {
+	for _, call := range b.calls {
+		call.Err = err
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package goya
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// rpcServer answers sum with the sum of the params and fail with an error, and records the notifications
+type rpcServer struct {
+	mu            sync.Mutex
+	notifications []string
+}
+
+func (s *rpcServer) answer(req map[string]json.RawMessage) any {
+	method := ""
+	json.Unmarshal(req["method"], &method)
+	id, ok := req["id"]
+	if !ok {
+		s.mu.Lock()
+		s.notifications = append(s.notifications, method)
+		s.mu.Unlock()
+		return nil
+	}
+	switch method {
+	case "sum":
+		params := []int{}
+		if err := json.Unmarshal(req["params"], &params); err != nil {
+			return map[string]any{"jsonrpc": "2.0", "id": id, "error": map[string]any{"code": RPCInvalidParams, "message": "Invalid params"}}
+		}
+		sum := 0
+		for _, p := range params {
+			sum += p
+		}
+		return map[string]any{"jsonrpc": "2.0", "id": id, "result": sum}
+	case "fail":
+		return map[string]any{"jsonrpc": "2.0", "id": id, "error": map[string]any{"code": -32000, "message": "failed", "data": "detail"}}
+	}
+	return map[string]any{"jsonrpc": "2.0", "id": id, "error": map[string]any{"code": RPCMethodNotFound, "message": "Method not found"}}
+}
+
+func (s *rpcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	raw := json.RawMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": nil, "error": map[string]any{"code": RPCParseError, "message": "Parse error"}})
+		return
+	}
+	if raw[0] != '[' {
+		req := map[string]json.RawMessage{}
+		json.Unmarshal(raw, &req)
+		if resp := s.answer(req); resp != nil {
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	reqs := []map[string]json.RawMessage{}
+	json.Unmarshal(raw, &reqs)
+	resps := []any{}
+	// answer in the reverse order, the responses of a batch can be in any order
+	for i := len(reqs) - 1; i >= 0; i-- {
+		if resp := s.answer(reqs[i]); resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	if len(resps) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	json.NewEncoder(w).Encode(resps)
+}
+
+func TestRPC(t *testing.T) {
+	server := &rpcServer{}
+	opt := NewOption(WithHandler(server))
+
+	got, err := RPC[int]("http://goya.test/rpc", "sum", []int{1, 2, 3}, opt)
+	if err != nil || got != 6 {
+		t.Errorf("RPC sum got %v, %v but want %v", got, err, 6)
+	}
+
+	_, err = RPC[int]("http://goya.test/rpc", "fail", nil, opt)
+	rpcErr := &RPCError{}
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32000 || rpcErr.Message != "failed" || string(rpcErr.Data) != `"detail"` {
+		t.Errorf("RPC fail got %v but want %v", err, "-32000 failed")
+	}
+
+	if err := RPCNotify("http://goya.test/rpc", "log", []string{"hello"}, opt); err != nil {
+		t.Errorf("RPCNotify got %v", err)
+	}
+	if len(server.notifications) != 1 || server.notifications[0] != "log" {
+		t.Errorf("notifications got %v but want %v", server.notifications, []string{"log"})
+	}
+}
+
+func TestRPCBatch(t *testing.T) {
+	server := &rpcServer{}
+	opt := NewOption(WithHandler(server))
+
+	batch := NewRPCBatch()
+	sum := batch.Call("sum", []int{1, 2})
+	invalid := batch.Call("sum", map[string]int{"a": 1})
+	missing := batch.Call("missing", nil)
+	batch.Notify("log", nil)
+	other := batch.Call("sum", []int{10, 20})
+	if err := batch.Do("http://goya.test/rpc", opt); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := RPCResult[int](sum); err != nil || got != 3 {
+		t.Errorf("sum got %v, %v but want %v", got, err, 3)
+	}
+	if got, err := RPCResult[int](other); err != nil || got != 30 {
+		t.Errorf("other sum got %v, %v but want %v", got, err, 30)
+	}
+	ts := []struct {
+		call *RPCCall
+		code int
+	}{
+		{invalid, RPCInvalidParams},
+		{missing, RPCMethodNotFound},
+	}
+	for _, tt := range ts {
+		rpcErr := &RPCError{}
+		if !errors.As(tt.call.Err, &rpcErr) || rpcErr.Code != tt.code {
+			t.Errorf("%v error got %v but want code %v", tt.call.Method, tt.call.Err, tt.code)
+		}
+	}
+	if len(server.notifications) != 1 {
+		t.Errorf("notifications got %v but want %v", server.notifications, []string{"log"})
+	}
+
+	notifications := NewRPCBatch()
+	notifications.Notify("a", nil)
+	notifications.Notify("b", nil)
+	if err := notifications.Do("http://goya.test/rpc", opt); err != nil {
+		t.Errorf("batch of notifications got %v", err)
+	}
+
+	parse := NewRPCBatch()
+	call := parse.Call("sum", nil)
+	err := parse.Do("http://goya.test/rpc", NewOption(WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`))
+	}))))
+	rpcErr := &RPCError{}
+	if !errors.As(err, &rpcErr) || rpcErr.Code != RPCParseError || call.Err != err {
+		t.Errorf("batch with a parse error got %v, %v but want code %v", err, call.Err, RPCParseError)
+	}
+
+	unanswered := NewRPCBatch()
+	call = unanswered.Call("sum", []int{1})
+	unanswered.Do("http://goya.test/rpc", NewOption(WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))))
+	if !errors.Is(call.Err, ErrRPCNoResponse) {
+		t.Errorf("unanswered call got %v but want %v", call.Err, ErrRPCNoResponse)
+	}
+}
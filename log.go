@@ -0,0 +1,214 @@
+package goya
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// LogOption changes what WithLogger logs
+type LogOption func(c *logConfig)
+
+type logConfig struct {
+	maxBody int
+	headers []string
+	params  []string
+}
+
+// LogBodies logs the bodies of the requests and the responses, truncated to max bytes
+// JSON is compacted, text and forms are logged as they are and binary bodies are only described
+func LogBodies(max int) LogOption {
+	return func(c *logConfig) {
+		c.maxBody = max
+	}
+}
+
+// LogRedactHeaders adds the headers whose values are replaced by REDACTED
+// Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key are redacted by default
+func LogRedactHeaders(headers ...string) LogOption {
+	return func(c *logConfig) {
+		c.headers = append(c.headers, headers...)
+	}
+}
+
+// LogRedactQuery adds the query params whose values are replaced by REDACTED
+// api_key, apikey, key, token, access_token, password, secret and signature are redacted by default
+func LogRedactQuery(params ...string) LogOption {
+	return func(c *logConfig) {
+		c.params = append(c.params, params...)
+	}
+}
+
+// WithLogger will log each request and response to the logger at level as structured attributes
+// Failed requests are logged at slog.LevelError, and a nil logger means slog.Default()
+func WithLogger(logger *slog.Logger, level slog.Level, opts ...LogOption) OptionFunc {
+	config := logConfig{
+		headers: append([]string{}, defaultRedactedHeaders...),
+		params:  append([]string{}, defaultRedactedParams...),
+	}
+	for _, f := range opts {
+		f(&config)
+	}
+	return WithObserver(&logObserver{logger: logger, level: level, config: config})
+}
+
+// logObserver is the Observer behind WithLogger
+type logObserver struct {
+	NopObserver
+	logger *slog.Logger
+	level  slog.Level
+	config logConfig
+}
+
+func (o *logObserver) OnSend(req *http.Request) {
+	attempt, _ := attemptOf(req.Context())
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL, o.config.params)),
+		slog.Int("attempt", attempt),
+		slog.Int64("size", req.ContentLength),
+		headerAttr(redactHeader(req.Header, o.config.headers)),
+	}
+	if o.config.maxBody > 0 && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			bts, _ := io.ReadAll(io.LimitReader(body, int64(o.config.maxBody)+1))
+			body.Close()
+			attrs = append(attrs, slog.String("body", formatBody(bts, req.Header.Get(contentType), o.config.maxBody)))
+		}
+	}
+	o.log(req.Context(), o.level, "goya request", attrs...)
+}
+
+func (o *logObserver) OnResponse(req *http.Request, resp *http.Response, elapsed time.Duration) {
+	attempt, _ := attemptOf(req.Context())
+	o.log(req.Context(), o.level, "goya response",
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL, o.config.params)),
+		slog.Int("attempt", attempt),
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("duration", elapsed),
+		slog.Int64("size", resp.ContentLength),
+		headerAttr(redactHeader(resp.Header, o.config.headers)),
+	)
+	resp.Body = &loggedBody{ReadCloser: resp.Body, observer: o, req: req, resp: resp, start: time.Now()}
+}
+
+func (o *logObserver) OnError(req *http.Request, err error) {
+	attempt, _ := attemptOf(req.Context())
+	o.log(req.Context(), slog.LevelError, "goya error",
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL, o.config.params)),
+		slog.Int("attempt", attempt),
+		slog.String("error", err.Error()),
+	)
+}
+
+func (o *logObserver) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	logger := o.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// loggedBody logs the size of the response body, and the body itself with LogBodies, once it has been read
+type loggedBody struct {
+	io.ReadCloser
+	observer *logObserver
+	req      *http.Request
+	resp     *http.Response
+	start    time.Time
+	size     int64
+	captured bytes.Buffer
+	once     sync.Once
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if max := b.observer.config.maxBody; max > 0 && b.captured.Len() <= max {
+		b.captured.Write(p[:min(n, max+1-b.captured.Len())])
+	}
+	if err != nil {
+		b.done(err)
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done(nil)
+	return err
+}
+
+func (b *loggedBody) done(err error) {
+	b.once.Do(func() {
+		attrs := []slog.Attr{
+			slog.String("method", b.req.Method),
+			slog.String("url", redactURL(b.req.URL, b.observer.config.params)),
+			slog.Int("status", b.resp.StatusCode),
+			slog.Int64("size", b.size),
+			slog.Duration("duration", time.Since(b.start)),
+		}
+		level := b.observer.level
+		if err != nil && err != io.EOF {
+			level = slog.LevelError
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		if b.observer.config.maxBody > 0 {
+			attrs = append(attrs, slog.String("body", formatBody(b.captured.Bytes(), b.resp.Header.Get(contentType), b.observer.config.maxBody)))
+		}
+		b.observer.log(b.req.Context(), level, "goya response body", attrs...)
+	})
+}
+
+func headerAttr(header http.Header) slog.Attr {
+	attrs := make([]any, 0, len(header))
+	for k, v := range header {
+		attrs = append(attrs, slog.String(k, strings.Join(v, ", ")))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// formatBody formats body according to the Content-Type and truncates it to max bytes
+func formatBody(body []byte, contentTypeValue string, max int) string {
+	truncated := len(body) > max
+	if truncated {
+		body = body[:max]
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentTypeValue)
+	var result string
+	switch {
+	case len(body) == 0:
+		return ""
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return "[multipart body]"
+	case mediaType == contentTypeJSON || strings.HasSuffix(mediaType, "+json"):
+		compacted := &bytes.Buffer{}
+		if !truncated && json.Compact(compacted, body) == nil {
+			result = compacted.String()
+		} else {
+			result = string(body)
+		}
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/x-www-form-urlencoded",
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"), mediaType == "":
+		if !utf8.Valid(body) && !truncated {
+			return "[binary body]"
+		}
+		result = string(body)
+	default:
+		return "[binary body]"
+	}
+	if truncated {
+		result += "...(truncated)"
+	}
+	return result
+}
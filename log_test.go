@@ -0,0 +1,85 @@
+package goya
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sagayosa/goya/goyatest"
+)
+
+func TestWithLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	resp := Post[BasicPostResponse]("http://goya.test/post", NewOption(
+		WithHandler(goyatest.NewHTTPBinHandler()),
+		WithLogger(logger, slog.LevelDebug, LogBodies(16)),
+		WithParams(map[string]string{"api_key": "secret-key", "page": "1"}),
+		WithForceHeader("Authorization", "Bearer secret-auth"),
+		WithJson(map[string]string{"name": "goya"}),
+	))
+	if resp.Data != `{"name":"goya"}` {
+		t.Errorf("resp.Data got %v but want %v", resp.Data, `{"name":"goya"}`)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("log contains the secrets : %v", buf.String())
+	}
+
+	records := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		record := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records got %v but want %v", len(records), 3)
+	}
+	request, response, body := records[0], records[1], records[2]
+	if request["msg"] != "goya request" || request["method"] != http.MethodPost || request["attempt"] != float64(1) {
+		t.Errorf("request got %v", request)
+	}
+	if request["body"] != `{"name":"goya"}` || request["size"] != float64(15) {
+		t.Errorf("request body got %v", request["body"])
+	}
+	if request["headers"].(map[string]any)["Authorization"] != redacted {
+		t.Errorf("request headers got %v", request["headers"])
+	}
+	if response["msg"] != "goya response" || response["status"] != float64(http.StatusOK) || response["level"] != "DEBUG" {
+		t.Errorf("response got %v", response)
+	}
+	if body["msg"] != "goya response body" || !strings.HasSuffix(body["body"].(string), "...(truncated)") || body["size"].(float64) <= 16 {
+		t.Errorf("response body got %v", body)
+	}
+
+	buf.Reset()
+	RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithTransport(goyatest.NewMock()), WithLogger(logger, slog.LevelInfo)))
+	if !strings.Contains(buf.String(), `"level":"ERROR","msg":"goya error"`) {
+		t.Errorf("error log got %v", buf.String())
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	ts := []struct {
+		body        string
+		contentType string
+		want        string
+	}{
+		{"{ \"a\": 1 }", "application/json; charset=utf-8", `{"a":1}`},
+		{"hello world", "text/plain", "hello worl...(truncated)"},
+		{"a=1&b=2", "application/x-www-form-urlencoded", "a=1&b=2"},
+		{"\x00\x01\x02", "application/octet-stream", "[binary body]"},
+		{"--boundary", "multipart/form-data; boundary=boundary", "[multipart body]"},
+	}
+	for _, tt := range ts {
+		max := 10
+		if got := formatBody([]byte(tt.body), tt.contentType, max); got != tt.want {
+			t.Errorf("formatBody(%q) got %q but want %q", tt.body, got, tt.want)
+		}
+	}
+}
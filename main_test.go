@@ -0,0 +1,45 @@
+package goya
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sagayosa/goya/goyatest"
+)
+
+// latency simulates the network so that TestTimeout still times out against the local server
+// It is added by the server, so it applies to the reused connections as well
+const latency = 20 * time.Millisecond
+
+// TestMain serves httpbin.org from goyatest.NewHTTPBinHandler so the tests do not need the network
+// Only the dials to httpbin.org:80 are changed, the other settings of http.DefaultTransport are kept
+func TestMain(m *testing.M) {
+	handler := goyatest.NewHTTPBinHandler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	addr := server.Listener.Addr().String()
+
+	dialer := &net.Dialer{}
+	transport := http.DefaultTransport.(*http.Transport)
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address == "httpbin.org:80" {
+			address = addr
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
@@ -0,0 +1,146 @@
+package goya
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Observer is notified of each step of a request, it can be used to plug in metrics and tracing
+// Embed NopObserver to only implement the callbacks you need
+type Observer interface {
+	// OnBuild is called after the request is built, errs are the errors that occurred during the build
+	OnBuild(req *http.Request, errs []error)
+	// OnSend is called before each request is sent, including the requests of the redirects
+	OnSend(req *http.Request)
+	// OnResponse is called when the header of each response is received
+	OnResponse(req *http.Request, resp *http.Response, elapsed time.Duration)
+	// OnRetry is called before goya sends a request again, such as a failed chunk of Download with DownloadParallel
+	// cause is the error that made the previous attempt fail
+	OnRetry(req *http.Request, attempt int, cause error)
+	// OnError is called when sending the request failed
+	OnError(req *http.Request, err error)
+	// OnBodyRead is called once the body of the response has been read to the end, failed or been closed
+	OnBodyRead(resp *Response, size int64, elapsed time.Duration, err error)
+}
+
+// NopObserver implements Observer with callbacks that do nothing
+type NopObserver struct{}
+
+func (NopObserver) OnBuild(req *http.Request, errs []error)                                  {}
+func (NopObserver) OnSend(req *http.Request)                                                 {}
+func (NopObserver) OnResponse(req *http.Request, resp *http.Response, elapsed time.Duration) {}
+func (NopObserver) OnRetry(req *http.Request, attempt int, cause error)                      {}
+func (NopObserver) OnError(req *http.Request, err error)                                     {}
+func (NopObserver) OnBodyRead(resp *Response, size int64, elapsed time.Duration, err error)  {}
+
+// WithObserver will notify the observer of each step of the request
+// Use it with SetDefaultOption to observe all requests
+func WithObserver(observer Observer) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		if observer == nil {
+			return func(b *RequestBuider) { b.ErrHappen(fmt.Errorf("WithObserver observer is nil")) }, nil, nil, nil
+		}
+		return func(b *RequestBuider) {
+			b.observers = append(b.observers, observer)
+		}, nil, nil, nil
+	}
+}
+
+type attemptKey struct{}
+
+type attempt struct {
+	number int
+	cause  error
+}
+
+// withAttempt marks the request as the attempt-th try of the same request, starting from 1
+// If attempt is greater than 1, the observers are notified by OnRetry with cause before it is sent
+func withAttempt(number int, cause error) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, func(req *http.Request) {
+			if req == nil {
+				return
+			}
+			*req = *req.WithContext(context.WithValue(req.Context(), attemptKey{}, attempt{number, cause}))
+		}, nil, nil
+	}
+}
+
+// attemptOf returns the attempt set by withAttempt, 1 if there is none
+func attemptOf(ctx context.Context) (int, error) {
+	a, ok := ctx.Value(attemptKey{}).(attempt)
+	if !ok {
+		return 1, nil
+	}
+	return a.number, a.cause
+}
+
+// observeClient returns a copy of client whose transport notifies the observers of each sent request
+func observeClient(client *http.Client, observers []Observer) *http.Client {
+	observed := *client
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	observed.Transport = &observedTransport{next: next, observers: observers}
+	return &observed
+}
+
+type observedTransport struct {
+	next      http.RoundTripper
+	observers []Observer
+}
+
+func (t *observedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, o := range t.observers {
+		o.OnSend(req)
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	for _, o := range t.observers {
+		o.OnResponse(req, resp, time.Since(start))
+	}
+	return resp, nil
+}
+
+// observedBody notifies the observers when the body has been read
+type observedBody struct {
+	io.ReadCloser
+	observers []Observer
+	resp      *Response
+	start     time.Time
+	size      int64
+	once      sync.Once
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if err == io.EOF {
+		b.notify(nil)
+	} else if err != nil {
+		b.notify(err)
+	}
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.notify(err)
+	return err
+}
+
+func (b *observedBody) notify(err error) {
+	b.once.Do(func() {
+		for _, o := range b.observers {
+			o.OnBodyRead(b.resp, b.size, time.Since(b.start), err)
+		}
+	})
+}
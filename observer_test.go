@@ -0,0 +1,109 @@
+package goya
+
+import (
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagayosa/goya/goyatest"
+)
+
+// eventObserver records the name of each callback
+type eventObserver struct {
+	mu     sync.Mutex
+	events []string
+	size   int64
+}
+
+func (o *eventObserver) add(event string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *eventObserver) OnBuild(req *http.Request, errs []error) { o.add("build") }
+func (o *eventObserver) OnSend(req *http.Request)                { o.add("send " + req.URL.Path) }
+func (o *eventObserver) OnResponse(req *http.Request, resp *http.Response, elapsed time.Duration) {
+	o.add("response " + resp.Status)
+}
+func (o *eventObserver) OnRetry(req *http.Request, attempt int, cause error) { o.add("retry") }
+func (o *eventObserver) OnError(req *http.Request, err error)                { o.add("error") }
+func (o *eventObserver) OnBodyRead(resp *Response, size int64, elapsed time.Duration, err error) {
+	o.size = size
+	o.add("body")
+}
+
+func TestWithObserver(t *testing.T) {
+	handler := WithHandler(goyatest.NewHTTPBinHandler())
+	observer := &eventObserver{}
+
+	resp := RequestRaw(http.MethodGet, "http://goya.test/redirect/1", NewOption(WithObserver(observer), handler))
+	body, _ := resp.Bytes()
+	want := []string{"build", "send /redirect/1", "response 302 Found", "send /get", "response 200 OK", "body"}
+	if !reflect.DeepEqual(observer.events, want) {
+		t.Errorf("events got %v but want %v", observer.events, want)
+	}
+	if observer.size != int64(len(body)) {
+		t.Errorf("size got %v but want %v", observer.size, len(body))
+	}
+
+	// the chunk of the second half fails once and is sent again
+	observer = &eventObserver{}
+	content := []byte("0123456789")
+	failed := false
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observer.mu.Lock()
+		fail := r.Header.Get("Range") == "bytes=5-9" && !failed
+		failed = failed || fail
+		observer.mu.Unlock()
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		downloadHandler(content, nil).ServeHTTP(w, r)
+	})
+	dest := filepath.Join(t.TempDir(), "file")
+	if _, err := Download("http://goya.test/file", dest, NewOption(WithHandler(flaky), WithObserver(observer)), DownloadParallel(2, 1)); err != nil {
+		t.Fatal(err)
+	}
+	retries := 0
+	for _, event := range observer.events {
+		if event == "retry" {
+			retries++
+		}
+	}
+	if retries != 1 {
+		t.Errorf("retries got %v but want %v in %v", retries, 1, observer.events)
+	}
+
+	observer = &eventObserver{}
+	RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithObserver(observer), WithTransport(goyatest.NewMock())))
+	want = []string{"build", "send /get", "error"}
+	if !reflect.DeepEqual(observer.events, want) {
+		t.Errorf("events got %v but want %v", observer.events, want)
+	}
+}
+
+func TestSetDefaultOption(t *testing.T) {
+	observer := &eventObserver{}
+	SetDefaultOption(NewOption(WithObserver(observer)))
+	defer SetDefaultOption(nil)
+
+	resp := Get[BasicGetResponse]("http://goya.test/get", NewOption(WithHandler(goyatest.NewHTTPBinHandler())))
+	if resp.URL != "http://goya.test/get" {
+		t.Errorf("resp.URL got %v but want %v", resp.URL, "http://goya.test/get")
+	}
+	want := []string{"build", "send /get", "response 200 OK", "body"}
+	if !reflect.DeepEqual(observer.events, want) {
+		t.Errorf("events got %v but want %v", observer.events, want)
+	}
+
+	// nil opt is allowed
+	RequestRaw(http.MethodGet, "://", nil)
+	if DefaultOption() == nil {
+		t.Error("DefaultOption got nil")
+	}
+}
@@ -1,18 +1,43 @@
 package goya
 
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Option keeps the funcs of the OptionFuncs of a request
+// The client built by its ClientBuildFuncs is kept as well, so the connection pool options, such as WithMaxConnsPerHost,
+// apply to the requests sharing the Option or a Session
 type Option struct {
 	before []BeforeBuildFunc
 	after  []AfterBuildFunc
 	client []ClientBuildFunc
 	done   []ClientDoneFunc
+
+	clients *clientCache
+}
+
+// clientCache keeps the client built by the ClientBuildFuncs of an Option,
+// so the requests sharing the Option share the transport and its connection pool as well
+type clientCache struct {
+	mu     sync.Mutex
+	client *http.Client
+	// merged keeps the caches of the Options concatenated after another one with ClientBuildFuncs, by the cache of that one
+	merged map[*clientCache]*clientCache
 }
 
+// defaultOption is applied before the Option of every request, see SetDefaultOption
+var defaultOption atomic.Pointer[Option]
+
 func NewOption(opts ...OptionFunc) *Option {
 	opt := &Option{
 		before: []BeforeBuildFunc{},
 		after:  []AfterBuildFunc{},
 		client: []ClientBuildFunc{},
 		done:   []ClientDoneFunc{},
+
+		clients: &clientCache{},
 	}
 	for _, f := range opts {
 		b, a, c, d := f()
@@ -31,3 +56,82 @@ func NewOption(opts ...OptionFunc) *Option {
 	}
 	return opt
 }
+
+// SetDefaultOption sets the Option applied before the Option of every request, such as WithObserver for metrics
+// Passing nil removes it
+func SetDefaultOption(opt *Option) {
+	defaultOption.Store(opt)
+}
+
+// DefaultOption returns the Option set by SetDefaultOption, nil if there is none
+func DefaultOption() *Option {
+	return defaultOption.Load()
+}
+
+// with returns a copy of the Option with opts appended after the existing ones,
+// so the original Option can be reused for several requests
+func (o *Option) with(opts ...OptionFunc) *Option {
+	return concatOption(o, NewOption(opts...))
+}
+
+// concatOption returns a new Option running the funcs of first and then the funcs of second
+// Either of them can be nil. If only one of them has ClientBuildFuncs, the new Option shares its client,
+// and if both have, it shares the client of the concatenations of the same pair
+func concatOption(first, second *Option) *Option {
+	opt := NewOption()
+	var clients *clientCache
+	for _, o := range []*Option{first, second} {
+		if o == nil {
+			continue
+		}
+		opt.before = append(opt.before, o.before...)
+		opt.after = append(opt.after, o.after...)
+		opt.done = append(opt.done, o.done...)
+		if len(o.client) > 0 {
+			if len(opt.client) == 0 {
+				clients = o.clients
+			} else {
+				clients = o.clients.after(clients)
+			}
+			opt.client = append(opt.client, o.client...)
+		}
+	}
+	if clients != nil {
+		opt.clients = clients
+	}
+	return opt
+}
+
+// buildClient returns a copy of the client built by the ClientBuildFuncs
+// The funcs are run once per Option, so the transport and its connection pool are reused by the requests
+func (o *Option) buildClient() *http.Client {
+	if o.clients == nil {
+		return newClient(o.client)
+	}
+	o.clients.mu.Lock()
+	defer o.clients.mu.Unlock()
+	if o.clients.client == nil {
+		o.clients.client = newClient(o.client)
+	}
+	client := *o.clients.client
+	return &client
+}
+
+// after returns the cache of the client built by the ClientBuildFuncs of the Option of first and then of c
+// It is kept by c, which is usually the cache of the Option of a request, so it does not grow with the requests
+func (c *clientCache) after(first *clientCache) *clientCache {
+	if c == nil || first == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.merged == nil {
+		c.merged = map[*clientCache]*clientCache{}
+	}
+	merged, ok := c.merged[first]
+	if !ok {
+		merged = &clientCache{}
+		c.merged[first] = merged
+	}
+	return merged
+}
@@ -2,6 +2,7 @@ package goya
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"mime/multipart"
@@ -199,3 +200,25 @@ func WithError(errs *[]error) OptionFunc {
 		}
 	}
 }
+
+// WithTransport will set the RoundTripper to *http.Client.Transport
+// It can be used to send the requests through a custom transport or a mock such as goyatest.Mock
+func WithTransport(transport http.RoundTripper) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			client.Transport = transport
+		}, nil
+	}
+}
+
+// WithInsecureSkipVerify will skip the verification of the server certificate, like curl -k
+// It should only be used for testing
+func WithInsecureSkipVerify() OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			configureTLS(client, func(c *tls.Config) {
+				c.InsecureSkipVerify = true
+			})
+		}, nil
+	}
+}
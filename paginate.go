@@ -0,0 +1,399 @@
+package goya
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxPages stops a Pager that would otherwise never end, such as a server returning the same cursor forever
+const defaultMaxPages = 1000
+
+// PageStrategy decides how the request of each page is built
+// You can implement it yourself if none of LinkPaging, CursorPaging, OffsetPaging and PageNumberPaging fits the API
+// A strategy keeping the position of the pages must not be shared by two Pagers, the strategies of goya are copied by Paginate
+type PageStrategy interface {
+	// First returns the options appended to the original Option for the first page
+	First() []OptionFunc
+	// Next returns the options appended to the original Option for the page after resp
+	// items is the number of items found in resp, ok is false when resp is the last page
+	Next(resp *Response, items int) (next []OptionFunc, ok bool, err error)
+}
+
+// PageOption changes how a Pager reads the pages
+type PageOption func(p *pagerConfig)
+
+type pagerConfig struct {
+	itemsPath string
+	maxPages  int
+}
+
+// ItemsAt sets the dotted JSON path of the items in each page, such as "data" or "result.items"
+// By default the whole body is expected to be a JSON array
+func ItemsAt(path string) PageOption {
+	return func(p *pagerConfig) {
+		p.itemsPath = path
+	}
+}
+
+// MaxPages sets the maximum number of pages that will be requested, 0 means no limit
+// By default a Pager stops after 1000 pages
+func MaxPages(n int) PageOption {
+	return func(p *pagerConfig) {
+		p.maxPages = n
+	}
+}
+
+type Page[T any] struct {
+	// Number starts from 1
+	Number   int
+	Items    []T
+	Response *Response
+}
+
+// Pager requests the pages one by one, it can be used like bufio.Scanner
+//
+//	for pager.Next() {
+//		page := pager.Page()
+//	}
+//	if err := pager.Err(); err != nil {
+//	}
+type Pager[T any] struct {
+	method   string
+	URL      string
+	opt      *Option
+	strategy PageStrategy
+	config   pagerConfig
+
+	next []OptionFunc
+	page *Page[T]
+	done bool
+	err  error
+}
+
+// Paginate returns a Pager that decodes the items of every page into T
+// Each page is requested with the original opt plus the options given by the strategy,
+// so headers, cookies, timeout and so on are kept for all pages
+func Paginate[T any](method, URL string, opt *Option, strategy PageStrategy, opts ...PageOption) *Pager[T] {
+	p := &Pager[T]{
+		method:   method,
+		URL:      URL,
+		opt:      opt,
+		strategy: strategy,
+		config:   pagerConfig{maxPages: defaultMaxPages},
+	}
+	for _, f := range opts {
+		f(&p.config)
+	}
+	if strategy == nil {
+		p.err = fmt.Errorf("Paginate strategy is nil")
+		p.done = true
+		return p
+	}
+	// The position of OffsetPaging and PageNumberPaging is kept per Pager
+	if c, ok := strategy.(interface{ clone() PageStrategy }); ok {
+		p.strategy = c.clone()
+	}
+	p.next = p.strategy.First()
+	return p
+}
+
+// Next requests the next page and reports whether it succeeded
+// It returns false when there are no more pages or an error occurred, check Err() for the latter
+func (p *Pager[T]) Next() bool {
+	if p.done {
+		return false
+	}
+	number := 1
+	if p.page != nil {
+		number = p.page.Number + 1
+	}
+	if p.config.maxPages > 0 && number > p.config.maxPages {
+		p.done = true
+		return false
+	}
+
+	client := NewRequestClient(p.method, p.URL, p.opt.with(p.next...), nil)
+	resp := client.Do()
+	if errs := client.Errors(); errs != nil {
+		return p.fail(fmt.Errorf("page %d : %w", number, errs[0]))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.RawResponse.Body.Close()
+		return p.fail(fmt.Errorf("page %d : unexpected status code %d", number, resp.StatusCode))
+	}
+	body, err := resp.Bytes()
+	if err != nil {
+		return p.fail(fmt.Errorf("page %d : %w", number, err))
+	}
+	raw, err := lookupJSONPath(body, p.config.itemsPath)
+	if err != nil {
+		return p.fail(fmt.Errorf("page %d : %w", number, err))
+	}
+	items := []T{}
+	if len(raw) != 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return p.fail(fmt.Errorf("page %d : %w", number, err))
+		}
+	}
+	p.page = &Page[T]{Number: number, Items: items, Response: resp}
+
+	next, ok, err := p.strategy.Next(resp, len(items))
+	if err != nil {
+		p.err = fmt.Errorf("page %d : %w", number, err)
+		ok = false
+	}
+	p.next = next
+	p.done = !ok
+	return true
+}
+
+// Page returns the page fetched by the last successful Next()
+func (p *Pager[T]) Page() *Page[T] {
+	return p.page
+}
+
+// Err returns the error that stopped the Pager, nil if it stopped because there were no more pages
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+// Pages returns an iterator over the remaining pages, it has the same shape as iter.Seq2[*Page[T], error]
+// An error is yielded once as the last element
+func (p *Pager[T]) Pages() func(yield func(*Page[T], error) bool) {
+	return func(yield func(*Page[T], error) bool) {
+		for p.Next() {
+			if !yield(p.page, nil) {
+				return
+			}
+		}
+		if p.err != nil {
+			yield(nil, p.err)
+		}
+	}
+}
+
+// Items returns an iterator over the items of the remaining pages, it has the same shape as iter.Seq2[T, error]
+// An error is yielded once as the last element
+func (p *Pager[T]) Items() func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		for p.Next() {
+			for _, item := range p.page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+		if p.err != nil {
+			var zero T
+			yield(zero, p.err)
+		}
+	}
+}
+
+func (p *Pager[T]) fail(err error) bool {
+	p.err = err
+	p.done = true
+	return false
+}
+
+type linkPaging struct{}
+
+// LinkPaging follows the URL of rel="next" in the Link header of each page (RFC 8288)
+func LinkPaging() PageStrategy {
+	return linkPaging{}
+}
+
+func (linkPaging) First() []OptionFunc {
+	return nil
+}
+
+func (linkPaging) Next(resp *Response, items int) ([]OptionFunc, bool, error) {
+	next := parseLinkHeader(resp.Header.Values("Link"))["next"]
+	if next == "" {
+		return nil, false, nil
+	}
+	if resp.RawResponse != nil && resp.RawResponse.Request != nil {
+		ref, err := url.Parse(next)
+		if err != nil {
+			return nil, false, fmt.Errorf("Link next is invalid : %w", err)
+		}
+		next = resp.RawResponse.Request.URL.ResolveReference(ref).String()
+	}
+	return []OptionFunc{withURL(next)}, true, nil
+}
+
+type cursorPaging struct {
+	param string
+	path  string
+}
+
+// CursorPaging sends the cursor found at the dotted JSON path of each page as the query param of the next page
+// It stops when the cursor is missing, null or empty
+func CursorPaging(param, path string) PageStrategy {
+	return cursorPaging{param: param, path: path}
+}
+
+func (c cursorPaging) First() []OptionFunc {
+	return nil
+}
+
+func (c cursorPaging) Next(resp *Response, items int) ([]OptionFunc, bool, error) {
+	raw, err := lookupJSONPath(resp.Body, c.path)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, false, nil
+	}
+	var cursor any
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, false, err
+	}
+	value := fmt.Sprintf("%v", cursor)
+	if f, ok := cursor.(float64); ok {
+		value = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if value == "" {
+		return nil, false, nil
+	}
+	return []OptionFunc{withQuery(c.param, value)}, true, nil
+}
+
+type offsetPaging struct {
+	offsetParam string
+	limitParam  string
+	limit       int
+	offset      int
+}
+
+// OffsetPaging sends offsetParam and limitParam as query params, starting from offset 0
+// It stops when a page has fewer items than limit
+func OffsetPaging(offsetParam, limitParam string, limit int) PageStrategy {
+	return &offsetPaging{offsetParam: offsetParam, limitParam: limitParam, limit: limit}
+}
+
+func (o *offsetPaging) clone() PageStrategy {
+	c := *o
+	return &c
+}
+
+func (o *offsetPaging) First() []OptionFunc {
+	o.offset = 0
+	return o.options()
+}
+
+func (o *offsetPaging) Next(resp *Response, items int) ([]OptionFunc, bool, error) {
+	if items < o.limit || items == 0 {
+		return nil, false, nil
+	}
+	o.offset += items
+	return o.options(), true, nil
+}
+
+func (o *offsetPaging) options() []OptionFunc {
+	return []OptionFunc{
+		withQuery(o.offsetParam, strconv.Itoa(o.offset)),
+		withQuery(o.limitParam, strconv.Itoa(o.limit)),
+	}
+}
+
+type pageNumberPaging struct {
+	param string
+	first int
+	page  int
+}
+
+// PageNumberPaging sends the page number as the query param, starting from first
+// It stops when a page has no items
+func PageNumberPaging(param string, first int) PageStrategy {
+	return &pageNumberPaging{param: param, first: first}
+}
+
+func (p *pageNumberPaging) clone() PageStrategy {
+	c := *p
+	return &c
+}
+
+func (p *pageNumberPaging) First() []OptionFunc {
+	p.page = p.first
+	return []OptionFunc{withQuery(p.param, strconv.Itoa(p.page))}
+}
+
+func (p *pageNumberPaging) Next(resp *Response, items int) ([]OptionFunc, bool, error) {
+	if items == 0 {
+		return nil, false, nil
+	}
+	p.page++
+	return []OptionFunc{withQuery(p.param, strconv.Itoa(p.page))}, true, nil
+}
+
+// withURL replaces the URL built by the previous options
+func withURL(URL string) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return func(b *RequestBuider) {
+			b.URL = URL
+		}, nil, nil, nil
+	}
+}
+
+// withQuery sets the query param of the URL built by the previous options, replacing the existing values
+func withQuery(key, value string) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return func(b *RequestBuider) {
+			parsedURL, err := url.Parse(b.URL)
+			if err != nil {
+				b.ErrHappen(fmt.Errorf("URL is invalid : %w", err))
+				return
+			}
+			querys := parsedURL.Query()
+			querys.Set(key, value)
+			parsedURL.RawQuery = querys.Encode()
+			b.URL = parsedURL.String()
+		}, nil, nil, nil
+	}
+}
+
+// parseLinkHeader returns the URL of each rel in the Link header values
+// such as <https://api.example.com/items?page=2>; rel="next", <https://api.example.com/items?page=5>; rel="last"
+func parseLinkHeader(values []string) map[string]string {
+	result := map[string]string{}
+	for _, value := range values {
+		for value != "" {
+			start := strings.IndexByte(value, '<')
+			if start < 0 {
+				break
+			}
+			end := strings.IndexByte(value[start:], '>')
+			if end < 0 {
+				break
+			}
+			link := value[start+1 : start+end]
+			value = value[start+end+1:]
+
+			params := value
+			if next := strings.IndexByte(value, '<'); next >= 0 {
+				params = value[:next]
+				value = value[next:]
+			} else {
+				value = ""
+			}
+			for _, param := range strings.Split(params, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(k), "rel") {
+					continue
+				}
+				v = strings.Trim(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), ",")), `"`)
+				for _, rel := range strings.Fields(v) {
+					rel = strings.ToLower(rel)
+					if _, exist := result[rel]; !exist {
+						result[rel] = link
+					}
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package goya
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// pageServer serves the numbers from 1 to 7 with the page size of 3
+func pageServer() *httptest.Server {
+	numbers := []int{1, 2, 3, 4, 5, 6, 7}
+	const size = 3
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Test-Header") != "paging" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		start := 0
+		switch r.URL.Path {
+		case "/link", "/page":
+			page, _ := strconv.Atoi(query.Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			start = (page - 1) * size
+			if r.URL.Path == "/link" && start+size < len(numbers) {
+				w.Header().Set("Link", fmt.Sprintf(`</link?page=%d>; rel="next", </link?page=3>; rel="last"`, page+1))
+			}
+		case "/cursor":
+			start, _ = strconv.Atoi(query.Get("cursor"))
+		case "/offset":
+			start, _ = strconv.Atoi(query.Get("offset"))
+		}
+		end := min(start+size, len(numbers))
+		start = min(start, end)
+		var cursor any
+		if end < len(numbers) {
+			cursor = strconv.Itoa(end)
+		}
+		if r.URL.Path == "/cursor" {
+			json.NewEncoder(w).Encode(map[string]any{"data": numbers[start:end], "meta": map[string]any{"next": cursor}})
+			return
+		}
+		json.NewEncoder(w).Encode(numbers[start:end])
+	}))
+}
+
+func TestPaginate(t *testing.T) {
+	server := pageServer()
+	defer server.Close()
+
+	want := []int{1, 2, 3, 4, 5, 6, 7}
+	ts := []struct {
+		path     string
+		strategy PageStrategy
+		opts     []PageOption
+	}{
+		{"/link", LinkPaging(), nil},
+		{"/cursor", CursorPaging("cursor", "meta.next"), []PageOption{ItemsAt("data")}},
+		{"/offset", OffsetPaging("offset", "limit", 3), nil},
+		{"/page", PageNumberPaging("page", 1), nil},
+	}
+	for _, tt := range ts {
+		opt := NewOption(WithForceHeader("Test-Header", "paging"))
+		pager := Paginate[int](http.MethodGet, server.URL+tt.path, opt, tt.strategy, tt.opts...)
+		got := []int{}
+		pages := 0
+		for pager.Next() {
+			pages++
+			got = append(got, pager.Page().Items...)
+		}
+		if err := pager.Err(); err != nil {
+			t.Fatalf("%v Err got %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v items got %v but want %v", tt.path, got, want)
+		}
+		if pages < 3 {
+			t.Errorf("%v pages got %v but want at least %v", tt.path, pages, 3)
+		}
+	}
+}
+
+func TestPaginateIterators(t *testing.T) {
+	server := pageServer()
+	defer server.Close()
+
+	opt := NewOption(WithForceHeader("Test-Header", "paging"))
+	got := []int{}
+	Paginate[int](http.MethodGet, server.URL+"/link", opt, LinkPaging()).Items()(func(item int, err error) bool {
+		if err != nil {
+			t.Fatalf("Items got error %v", err)
+		}
+		got = append(got, item)
+		return item < 4
+	})
+	if !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("Items got %v but want %v", got, []int{1, 2, 3, 4})
+	}
+
+	numbers := []int{}
+	Paginate[int](http.MethodGet, server.URL+"/page", opt, PageNumberPaging("page", 1), MaxPages(2)).Pages()(func(page *Page[int], err error) bool {
+		if err != nil {
+			t.Fatalf("Pages got error %v", err)
+		}
+		numbers = append(numbers, page.Number)
+		return true
+	})
+	if !reflect.DeepEqual(numbers, []int{1, 2}) {
+		t.Errorf("Pages got %v but want %v", numbers, []int{1, 2})
+	}
+
+	pager := Paginate[int](http.MethodGet, server.URL+"/page", NewOption(), PageNumberPaging("page", 1))
+	if pager.Next() {
+		t.Error("Next should be false without the header")
+	}
+	if pager.Err() == nil {
+		t.Error("Err got nil")
+	}
+}
+
+func TestPaginateSharedStrategy(t *testing.T) {
+	server := pageServer()
+	defer server.Close()
+
+	opt := NewOption(WithForceHeader("Test-Header", "paging"))
+	for _, strategy := range []PageStrategy{OffsetPaging("offset", "limit", 3), PageNumberPaging("page", 1)} {
+		path := "/offset"
+		if _, ok := strategy.(*pageNumberPaging); ok {
+			path = "/page"
+		}
+		first := Paginate[int](http.MethodGet, server.URL+path, opt, strategy)
+		second := Paginate[int](http.MethodGet, server.URL+path, opt, strategy)
+		got := [2][]int{}
+		for first.Next() && second.Next() {
+			got[0] = append(got[0], first.Page().Items...)
+			got[1] = append(got[1], second.Page().Items...)
+		}
+		for i := range got {
+			if !reflect.DeepEqual(got[i], []int{1, 2, 3, 4, 5, 6, 7}) {
+				t.Errorf("%v pager %d got %v but want %v", path, i, got[i], []int{1, 2, 3, 4, 5, 6, 7})
+			}
+		}
+	}
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	links := parseLinkHeader([]string{`<https://a.com/x?page=2&a=b,c>; rel="next prefetch", <https://a.com/x?page=1>; rel=prev`, `<https://a.com/x?page=9>;rel="last"`})
+	want := map[string]string{
+		"next":     "https://a.com/x?page=2&a=b,c",
+		"prefetch": "https://a.com/x?page=2&a=b,c",
+		"prev":     "https://a.com/x?page=1",
+		"last":     "https://a.com/x?page=9",
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("parseLinkHeader got %v but want %v", links, want)
+	}
+}
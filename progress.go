@@ -0,0 +1,62 @@
+package goya
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// progressInterval is the min interval between two progress callbacks, the last one is always called
+const progressInterval = 100 * time.Millisecond
+
+// WithUploadProgress will call f while the request body is sent with the bytes sent so far
+// and the size of the body, which is -1 if it is unknown
+// The body is wrapped when it is sent, so it works with the options changing the body in any order, and with the body sent again by a redirect
+func WithUploadProgress(f func(sent, total int64)) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		if f == nil {
+			return nil, nil, nil, nil
+		}
+		return nil, withSendBody(func(body io.ReadCloser, req *http.Request) io.ReadCloser {
+			return &progressReader{ReadCloser: body, total: req.ContentLength, f: f}
+		}), nil, nil
+	}
+}
+
+// WithDownloadProgress will call f while the response body is read with the bytes read so far
+// and the size of the body, which is -1 if it is unknown
+func WithDownloadProgress(f func(read, total int64)) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, nil, func(errs []error, resp *Response) {
+			if resp.RawResponse == nil || resp.RawResponse.Body == nil || f == nil {
+				return
+			}
+			resp.RawResponse.Body = &progressReader{ReadCloser: resp.RawResponse.Body, total: resp.RawResponse.ContentLength, f: f}
+		}
+	}
+}
+
+// progressReader calls f at most once per progressInterval, and once more at the end of the body
+type progressReader struct {
+	io.ReadCloser
+	read  int64
+	total int64
+	last  time.Time
+	done  bool
+	f     func(read, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.ReadCloser.Read(b)
+	p.read += int64(n)
+	if p.done {
+		return n, err
+	}
+	end := err == io.EOF || (p.total >= 0 && p.read >= p.total)
+	if end || (n > 0 && time.Since(p.last) >= progressInterval) {
+		p.last = time.Now()
+		p.done = end
+		p.f(p.read, p.total)
+	}
+	return n, err
+}
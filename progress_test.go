@@ -0,0 +1,78 @@
+package goya
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestWithProgress(t *testing.T) {
+	content := bytes.Repeat([]byte("goya"), 1<<16)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Length", "262144")
+		w.Write(content)
+	})
+
+	uploads, downloads := [][2]int64{}, [][2]int64{}
+	resp := RequestRaw(http.MethodPost, "http://goya.test/post", NewOption(
+		WithHandler(handler),
+		withRawBody(content, "application/octet-stream"),
+		WithUploadProgress(func(sent, total int64) { uploads = append(uploads, [2]int64{sent, total}) }),
+		WithDownloadProgress(func(read, total int64) { downloads = append(downloads, [2]int64{read, total}) }),
+	))
+	if _, err := resp.Bytes(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [2]int64{int64(len(content)), int64(len(content))}
+	if len(uploads) == 0 || uploads[len(uploads)-1] != want {
+		t.Errorf("uploads got %v but want the last one %v", uploads, want)
+	}
+	if len(downloads) == 0 || downloads[len(downloads)-1] != want {
+		t.Errorf("downloads got %v but want the last one %v", downloads, want)
+	}
+	// The callbacks are throttled, so there is one for the first read and one for the end
+	if len(downloads) > 2 {
+		t.Errorf("len(downloads) got %v but want at most %v", len(downloads), 2)
+	}
+}
+
+func TestWithUploadProgressOrder(t *testing.T) {
+	content := bytes.Repeat([]byte("goya"), 1<<10)
+	received := []int{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bts, _ := io.ReadAll(r.Body)
+		received = append(received, len(bts))
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/post", http.StatusTemporaryRedirect)
+		}
+	})
+
+	// the body is compressed after the progress option is applied
+	uploads := [][2]int64{}
+	RequestRaw(http.MethodPost, "http://goya.test/redirect", NewOption(
+		WithHandler(handler),
+		withRawBody(content, "application/octet-stream"),
+		WithUploadProgress(func(sent, total int64) { uploads = append(uploads, [2]int64{sent, total}) }),
+		WithRequestCompression(CompressionGzip),
+	)).Bytes()
+	if len(received) != 2 || received[0] != received[1] || received[0] >= len(content) {
+		t.Fatalf("received got %v but want 2 compressed bodies", received)
+	}
+	// the body is sent again by the redirect, so there are two ends
+	want := [2]int64{int64(received[0]), int64(received[0])}
+	ends := 0
+	for _, u := range uploads {
+		if u == want {
+			ends++
+		}
+		if u[1] != want[1] {
+			t.Errorf("upload total got %v but want %v", u[1], want[1])
+		}
+	}
+	if ends != 2 {
+		t.Errorf("uploads got %v but want 2 ends of %v", uploads, want)
+	}
+}
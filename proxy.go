@@ -0,0 +1,188 @@
+package goya
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// WithProxy will send the requests through the proxy at proxyURL, whose scheme is http, https or socks5
+// The user info of proxyURL is sent in Proxy-Authorization for http and https proxies,
+// and as the username/password authentication for socks5 proxies
+// The hosts matching noProxy are connected directly, each rule has the format of the NO_PROXY environment variable,
+// see WithProxyFromEnvironment
+func WithProxy(proxyURL string, noProxy ...string) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		u, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return func(b *RequestBuider) { b.ErrHappen(fmt.Errorf("WithProxy : %w", err)) }, nil, nil, nil
+		}
+		rules := parseNoProxy(strings.Join(noProxy, ","))
+		return nil, nil, func(client *http.Client) {
+			configureTransport(client, func(t *http.Transport) {
+				t.Proxy = func(req *http.Request) (*url.URL, error) {
+					if !rules.useProxy(req.URL) {
+						return nil, nil
+					}
+					return u, nil
+				}
+			})
+		}, nil
+	}
+}
+
+// WithProxyFromEnvironment will send the requests through the proxy of HTTP_PROXY or HTTPS_PROXY
+// according to the scheme of the request, the hosts matching NO_PROXY are connected directly.
+// The lowercase names are used if the uppercase ones are not set
+//
+// NO_PROXY is a comma separated list of rules, * matches every host.
+// A rule is an IP, a CIDR or a domain with an optional port, and a domain matches its subdomains as well,
+// or only them if it starts with a dot. localhost and the loopback addresses are always connected directly
+//
+// Unlike http.ProxyFromEnvironment the variables are read each time the option is applied
+func WithProxyFromEnvironment() OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		proxies := map[string]*url.URL{}
+		for scheme, names := range map[string][]string{
+			"http":  {"HTTP_PROXY", "http_proxy"},
+			"https": {"HTTPS_PROXY", "https_proxy"},
+		} {
+			value := getenv(names...)
+			if value == "" {
+				continue
+			}
+			u, err := parseProxyURL(value)
+			if err != nil {
+				return func(b *RequestBuider) { b.ErrHappen(fmt.Errorf("WithProxyFromEnvironment %v : %w", names[0], err)) }, nil, nil, nil
+			}
+			proxies[scheme] = u
+		}
+		// Like http.ProxyFromEnvironment, the loopback addresses are connected directly
+		rules := parseNoProxy("localhost,127.0.0.0/8,::1," + getenv("NO_PROXY", "no_proxy"))
+		return nil, nil, func(client *http.Client) {
+			configureTransport(client, func(t *http.Transport) {
+				t.Proxy = func(req *http.Request) (*url.URL, error) {
+					u, ok := proxies[req.URL.Scheme]
+					if !ok || !rules.useProxy(req.URL) {
+						return nil, nil
+					}
+					return u, nil
+				}
+			})
+		}, nil
+	}
+}
+
+func getenv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseProxyURL parses the proxy, a proxy without a scheme such as host:port is an http proxy
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		// host:port is parsed as a scheme and an opaque
+		if withScheme, schemeErr := url.Parse("http://" + proxyURL); schemeErr == nil && withScheme.Host != "" {
+			u, err = withScheme, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return u, nil
+	}
+	return nil, fmt.Errorf("the scheme of the proxy %v is not supported", u.Scheme)
+}
+
+type noProxyRule struct {
+	ip     net.IP
+	cidr   *net.IPNet
+	domain string
+	// subdomainOnly is true if the rule starts with a dot
+	subdomainOnly bool
+	port          string
+}
+
+type noProxyRules struct {
+	all   bool
+	rules []noProxyRule
+}
+
+func parseNoProxy(value string) noProxyRules {
+	result := noProxyRules{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "":
+			continue
+		case item == "*":
+			result.all = true
+			continue
+		}
+		if _, cidr, err := net.ParseCIDR(item); err == nil {
+			result.rules = append(result.rules, noProxyRule{cidr: cidr})
+			continue
+		}
+		host, port, err := net.SplitHostPort(item)
+		if err != nil {
+			host, port = item, ""
+		}
+		host = strings.Trim(host, "[]")
+		if ip := net.ParseIP(host); ip != nil {
+			result.rules = append(result.rules, noProxyRule{ip: ip, port: port})
+			continue
+		}
+		rule := noProxyRule{domain: strings.TrimPrefix(host, "*"), port: port}
+		if strings.HasPrefix(rule.domain, ".") {
+			rule.subdomainOnly = true
+			rule.domain = rule.domain[1:]
+		}
+		result.rules = append(result.rules, rule)
+	}
+	return result
+}
+
+// useProxy reports whether the request to u should be sent through the proxy
+func (r noProxyRules) useProxy(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if r.all {
+		return false
+	}
+	port := u.Port()
+	if port == "" && u.Scheme == "https" {
+		port = "443"
+	} else if port == "" {
+		port = "80"
+	}
+	ip := net.ParseIP(host)
+	for _, rule := range r.rules {
+		switch {
+		case rule.cidr != nil:
+			if ip != nil && rule.cidr.Contains(ip) {
+				return false
+			}
+		case rule.ip != nil:
+			if ip != nil && rule.ip.Equal(ip) && (rule.port == "" || rule.port == port) {
+				return false
+			}
+		default:
+			if rule.port != "" && rule.port != port {
+				continue
+			}
+			if strings.HasSuffix(host, "."+rule.domain) || (!rule.subdomainOnly && host == rule.domain) {
+				return false
+			}
+		}
+	}
+	return true
+}
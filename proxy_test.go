@@ -0,0 +1,149 @@
+package goya
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWithProxy(t *testing.T) {
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != auth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		fmt.Fprintf(w, "proxied %v", r.URL)
+	}))
+	defer proxy.Close()
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://user:pass@", 1)
+
+	got, _ := RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithProxy(proxyURL))).String()
+	if got != "proxied http://goya.test/get" {
+		t.Errorf("body got %v but want %v", got, "proxied http://goya.test/get")
+	}
+	if resp := RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithProxy(proxy.URL))); resp.StatusCode != http.StatusProxyAuthRequired {
+		t.Errorf("resp.StatusCode without auth got %v but want %v", resp.StatusCode, http.StatusProxyAuthRequired)
+	}
+
+	t.Setenv("HTTP_PROXY", proxyURL)
+	t.Setenv("NO_PROXY", "example.com")
+	got, _ = RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithProxyFromEnvironment())).String()
+	if got != "proxied http://goya.test/get" {
+		t.Errorf("body got %v but want %v", got, "proxied http://goya.test/get")
+	}
+
+	errs := []error{}
+	RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithProxy("ftp://proxy.test"), WithError(&errs)))
+	if len(errs) == 0 {
+		t.Errorf("WithProxy with ftp got no error")
+	}
+}
+
+func TestWithProxySOCKS5(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello %v", r.Host)
+	}))
+	defer backend.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	targets := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(conn, backend.Listener.Addr().String(), targets)
+		}
+	}()
+
+	got, _ := RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithProxy("socks5://user:pass@"+ln.Addr().String()))).String()
+	if got != "hello goya.test" {
+		t.Errorf("body got %v but want %v", got, "hello goya.test")
+	}
+	if target := <-targets; target != "goya.test:80" {
+		t.Errorf("target got %v but want %v", target, "goya.test:80")
+	}
+}
+
+// serveSOCKS5 serves one connection with the username/password authentication user:pass, and connects to backend
+func serveSOCKS5(conn net.Conn, backend string, targets chan<- string) {
+	defer conn.Close()
+	buf := make([]byte, 512)
+	// version, methods
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	io.ReadFull(conn, buf[:buf[1]])
+	conn.Write([]byte{5, 2})
+	// username/password
+	io.ReadFull(conn, buf[:2])
+	user := make([]byte, buf[1])
+	io.ReadFull(conn, user)
+	io.ReadFull(conn, buf[:1])
+	pass := make([]byte, buf[0])
+	io.ReadFull(conn, pass)
+	if string(user) != "user" || string(pass) != "pass" {
+		conn.Write([]byte{1, 1})
+		return
+	}
+	conn.Write([]byte{1, 0})
+	// CONNECT with a domain name
+	io.ReadFull(conn, buf[:5])
+	host := make([]byte, buf[4])
+	io.ReadFull(conn, host)
+	io.ReadFull(conn, buf[:2])
+	targets <- fmt.Sprintf("%s:%d", host, binary.BigEndian.Uint16(buf[:2]))
+
+	upstream, err := net.Dial("tcp", backend)
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func TestNoProxy(t *testing.T) {
+	rules := parseNoProxy("example.com, .internal.test, 10.0.0.0/8, 192.168.1.1, api.test:8443, [::2]")
+	ts := []struct {
+		URL  string
+		want bool
+	}{
+		{"http://example.com", false},
+		{"http://www.example.com", false},
+		{"http://notexample.com", true},
+		{"http://internal.test", true},
+		{"http://svc.internal.test", false},
+		{"http://10.1.2.3", false},
+		{"http://192.168.1.1:8080", false},
+		{"http://192.168.1.2", true},
+		{"https://api.test:8443", false},
+		{"https://api.test", true},
+		{"http://[::2]", false},
+		{"http://localhost", true},
+	}
+	for _, tt := range ts {
+		u, _ := url.Parse(tt.URL)
+		if got := rules.useProxy(u); got != tt.want {
+			t.Errorf("useProxy(%v) got %v but want %v", tt.URL, got, tt.want)
+		}
+	}
+	u, _ := url.Parse("http://goya.test")
+	if parseNoProxy("*").useProxy(u) {
+		t.Errorf("useProxy with * got true but want false")
+	}
+}
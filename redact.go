@@ -0,0 +1,66 @@
+package goya
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces the values of the secrets
+const redacted = "REDACTED"
+
+// defaultRedactedHeaders are the headers carrying credentials
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// defaultRedactedParams are the common query params carrying credentials
+var defaultRedactedParams = []string{"api_key", "apikey", "key", "token", "access_token", "password", "secret", "signature"}
+
+// redactHeader returns a copy of header whose values of names are replaced by REDACTED
+func redactHeader(header http.Header, names []string) http.Header {
+	result := header.Clone()
+	if result == nil {
+		return http.Header{}
+	}
+	for _, name := range names {
+		values := result.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		result.Del(name)
+		for range values {
+			result.Add(name, redacted)
+		}
+	}
+	return result
+}
+
+// redactURL returns the URL whose query params in params and password are replaced by REDACTED
+// The names of the params are case-insensitive
+func redactURL(u *url.URL, params []string) string {
+	if u == nil {
+		return ""
+	}
+	result := *u
+	if _, ok := result.User.Password(); ok {
+		result.User = url.UserPassword(result.User.Username(), redacted)
+	}
+	if result.RawQuery == "" {
+		return result.String()
+	}
+	querys := result.Query()
+	changed := false
+	for k, v := range querys {
+		for _, p := range params {
+			if strings.EqualFold(k, p) {
+				for i := range v {
+					v[i] = redacted
+				}
+				changed = true
+			}
+		}
+	}
+	if changed {
+		result.RawQuery = querys.Encode()
+	}
+	return result.String()
+}
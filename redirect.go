@@ -0,0 +1,71 @@
+package goya
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Redirect is a redirect response that was followed
+type Redirect struct {
+	// URL is the URL of the request that got the redirect response
+	URL        string
+	StatusCode int
+	// Location is the URL that the redirect points to
+	Location string
+	Header   http.Header
+}
+
+// WithRedirectPolicy will follow at most max redirects, the request fails if there are more
+// If sameHostOnly is true the request fails when it is redirected to another host.
+// net/http drops Authorization, WWW-Authenticate and Cookie when redirected to another domain,
+// they are sent again if keepAuthHeaders is true
+func WithRedirectPolicy(max int, sameHostOnly, keepAuthHeaders bool) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+				if len(via) > max {
+					return fmt.Errorf("stopped after %d redirects", max)
+				}
+				if sameHostOnly && req.URL.Host != via[0].URL.Host {
+					return fmt.Errorf("redirected to another host %v", req.URL.Host)
+				}
+				if keepAuthHeaders {
+					for _, k := range []string{"Authorization", "Www-Authenticate", "Cookie"} {
+						if _, ok := req.Header[k]; !ok && len(via[0].Header[k]) > 0 {
+							req.Header[k] = via[0].Header[k]
+						}
+					}
+				}
+				return nil
+			}
+		}, nil
+	}
+}
+
+// WithNoRedirect will not follow the redirects, the redirect response is returned as the Response
+func WithNoRedirect() OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			}
+		}, nil
+	}
+}
+
+// redirectsOf walks back the responses that led to resp
+func redirectsOf(resp *http.Response) []Redirect {
+	redirects := []Redirect{}
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		r := Redirect{
+			StatusCode: req.Response.StatusCode,
+			Location:   req.Response.Header.Get("Location"),
+			Header:     req.Response.Header,
+		}
+		if req.Response.Request != nil {
+			r.URL = req.Response.Request.URL.String()
+		}
+		redirects = append([]Redirect{r}, redirects...)
+	}
+	return redirects
+}
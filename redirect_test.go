@@ -0,0 +1,59 @@
+package goya
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedirects(t *testing.T) {
+	resp := RequestRaw(http.MethodGet, "http://httpbin.org/redirect/2", nil)
+	if resp.StatusCode != http.StatusOK || len(resp.Redirects) != 2 {
+		t.Fatalf("resp got %v with %v redirects", resp.StatusCode, len(resp.Redirects))
+	}
+	first := resp.Redirects[0]
+	if first.URL != "http://httpbin.org/redirect/2" || first.StatusCode != http.StatusFound || first.Location != "/redirect/1" {
+		t.Errorf("first redirect got %+v", first)
+	}
+	if second := resp.Redirects[1]; second.URL != "http://httpbin.org/redirect/1" || second.Location != "/get" {
+		t.Errorf("second redirect got %+v", second)
+	}
+
+	resp = RequestRaw(http.MethodGet, "http://httpbin.org/redirect/2", NewOption(WithNoRedirect()))
+	if resp.StatusCode != http.StatusFound || len(resp.Redirects) != 0 || resp.Header.Get("Location") != "/redirect/1" {
+		t.Errorf("resp without redirect got %v %v", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	errs := []error{}
+	RequestRaw(http.MethodGet, "http://httpbin.org/redirect/3", NewOption(WithRedirectPolicy(2, false, false), WithError(&errs)))
+	if len(errs) == 0 {
+		t.Errorf("3 redirects with max 2 got no error")
+	}
+	errs = []error{}
+	RequestRaw(http.MethodGet, "http://httpbin.org/redirect/2", NewOption(WithRedirectPolicy(2, true, false), WithError(&errs)))
+	if len(errs) != 0 {
+		t.Errorf("2 redirects with max 2 got %v", errs)
+	}
+}
+
+func TestWithRedirectPolicyHosts(t *testing.T) {
+	handler := WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "login.test" {
+			http.Redirect(w, r, "http://app.test/home", http.StatusFound)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	auth := WithForceHeader("Authorization", "Bearer goya")
+
+	if got, _ := RequestRaw(http.MethodGet, "http://login.test/", NewOption(handler, auth)).String(); got != "" {
+		t.Errorf("Authorization got %v but want it dropped", got)
+	}
+	if got, _ := RequestRaw(http.MethodGet, "http://login.test/", NewOption(handler, auth, WithRedirectPolicy(10, false, true))).String(); got != "Bearer goya" {
+		t.Errorf("Authorization got %v but want %v", got, "Bearer goya")
+	}
+	errs := []error{}
+	RequestRaw(http.MethodGet, "http://login.test/", NewOption(handler, WithRedirectPolicy(10, true, false), WithError(&errs)))
+	if len(errs) == 0 {
+		t.Errorf("the redirect to another host got no error")
+	}
+}
@@ -10,7 +10,8 @@ type RequestBuider struct {
 	originURL string
 	Opt       *Option
 
-	errs []error
+	errs      []error
+	observers []Observer
 	// URL will be passed to NewRequest to create *http.Request,
 	// so you can directly modify this field to get expected request
 	URL string
@@ -38,6 +39,7 @@ func (b *RequestBuider) Build() *http.Request {
 	request, err := http.NewRequest(b.method, b.URL, bytes.NewBuffer(b.Body))
 	if err != nil {
 		b.ErrHappen(err)
+		return nil
 	}
 
 	for _, after := range b.Opt.after {
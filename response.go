@@ -15,6 +15,10 @@ type Response struct {
 
 	// You can get it after using Bytes() or String()
 	Body []byte
+	// Timings is only recorded with WithTimings()
+	Timings *Timings
+	// Redirects are the responses followed before RawResponse, the first one is the response to the original request
+	Redirects []Redirect
 }
 
 // Bytes will read the body and return the result in []byte
@@ -28,7 +32,7 @@ func (r *Response) Bytes() ([]byte, error) {
 	}
 	bts, err := io.ReadAll(r.RawResponse.Body)
 	if err != nil {
-		return bts, nil
+		return bts, err
 	}
 	r.RawResponse.Body.Close()
 	r.Body = bts
@@ -58,5 +62,6 @@ func NewResponse(resp *http.Response) *Response {
 		StatusCode:  resp.StatusCode,
 		Header:      resp.Header,
 		RawResponse: resp,
+		Redirects:   redirectsOf(resp),
 	}
 }
@@ -0,0 +1,159 @@
+package goya
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+)
+
+// Session shares one transport, and so its connection pool, between the requests using WithSession
+// The requests sharing an Option share its transport as well, a Session is needed to share it between different Options
+type Session struct {
+	transport *http.Transport
+	errs      []error
+
+	mu    sync.Mutex
+	conns map[*sessionConn]bool
+}
+
+// PoolStats are the connections to a host in the pool of a Session
+// They are accurate for HTTP/1, an HTTP/2 connection is active while it has a request
+type PoolStats struct {
+	// Active is the number of connections with a request in flight
+	Active int
+	// Idle is the number of connections waiting in the pool to be reused
+	Idle int
+}
+
+// NewSession creates the transport of the session with the options changing the transport,
+// such as WithProxy, WithRootCAs, WithResolve or WithMaxConnsPerHost. The other parts of the options are ignored,
+// and the transport options of the requests using the session are ignored as well
+func NewSession(opts ...OptionFunc) *Session {
+	opt := NewOption(opts...)
+	builder := NewRequestBuilder(http.MethodGet, "", opt)
+	for _, before := range opt.before {
+		before(builder)
+	}
+	client := newClient(opt.client)
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	// The dialer is wrapped below, so a transport given by WithTransport is not changed
+	transport = transport.Clone()
+
+	s := &Session{transport: transport, errs: builder.Errors(), conns: map[*sessionConn]bool{}}
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		c := &sessionConn{Conn: conn, session: s, addr: addr}
+		s.mu.Lock()
+		s.conns[c] = false
+		s.mu.Unlock()
+		return c, nil
+	}
+	return s
+}
+
+// WithSession will send the request with the transport of s, the errors of the options of NewSession are reported as well
+func WithSession(s *Session) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		if s == nil {
+			return func(b *RequestBuider) { b.ErrHappen(fmt.Errorf("WithSession session is nil")) }, nil, nil, nil
+		}
+		return func(b *RequestBuider) {
+				for _, err := range s.errs {
+					b.ErrHappen(err)
+				}
+			}, nil, func(client *http.Client) {
+				client.Transport = &sessionTransport{session: s}
+			}, nil
+	}
+}
+
+// PoolStats returns the connections of the pool by host:port
+func (s *Session) PoolStats() map[string]PoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := map[string]PoolStats{}
+	for c, active := range s.conns {
+		stats := result[c.addr]
+		if active {
+			stats.Active++
+		} else {
+			stats.Idle++
+		}
+		result[c.addr] = stats
+	}
+	return result
+}
+
+// CloseIdleConnections closes the idle connections of the pool
+func (s *Session) CloseIdleConnections() {
+	s.transport.CloseIdleConnections()
+}
+
+func (s *Session) setActive(c *sessionConn, active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.conns[c]; ok {
+		s.conns[c] = active
+	}
+}
+
+// sessionTransport is not an *http.Transport, so the transport options of a request do not change the session
+type sessionTransport struct {
+	session *Session
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s := t.session
+	// conn is the connection of the request, PutIdleConn is called by the goroutine of the connection
+	var conn atomic.Pointer[sessionConn]
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			c := info.Conn
+			if tlsConn, ok := c.(*tls.Conn); ok {
+				c = tlsConn.NetConn()
+			}
+			if sc, ok := c.(*sessionConn); ok {
+				conn.Store(sc)
+				s.setActive(sc, true)
+			}
+		},
+		PutIdleConn: func(err error) {
+			if c := conn.Load(); c != nil && err == nil {
+				s.setActive(c, false)
+			}
+		},
+	}
+	return s.transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// sessionConn removes itself from the pool stats when it is closed
+type sessionConn struct {
+	net.Conn
+	session *Session
+	addr    string
+	once    sync.Once
+}
+
+func (c *sessionConn) Close() error {
+	c.once.Do(func() {
+		c.session.mu.Lock()
+		delete(c.session.conns, c)
+		c.session.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
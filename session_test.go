@@ -0,0 +1,126 @@
+package goya
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			<-release
+		}
+		w.Write([]byte("goya"))
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	session := NewSession(WithMaxIdleConnsPerHost(4), WithIdleConnTimeout(time.Minute))
+	opt := NewOption(WithSession(session))
+	for i := 0; i < 3; i++ {
+		RequestRaw(http.MethodGet, server.URL, opt).Bytes()
+	}
+	if got, want := session.PoolStats()[host], (PoolStats{Active: 0, Idle: 1}); got != want {
+		t.Errorf("stats after sequential requests got %+v but want %+v", got, want)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RequestRaw(http.MethodGet, server.URL+"/block", opt).Bytes()
+		}()
+	}
+	for deadline := time.Now().Add(time.Second); session.PoolStats()[host].Active < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, want := session.PoolStats()[host], (PoolStats{Active: 2, Idle: 0}); got != want {
+		t.Errorf("stats while the requests are in flight got %+v but want %+v", got, want)
+	}
+	close(release)
+	wg.Wait()
+	if got := session.PoolStats()[host]; got.Active != 0 || got.Idle != 2 {
+		t.Errorf("stats after the requests got %+v", got)
+	}
+
+	session.CloseIdleConnections()
+	if got := session.PoolStats()[host]; got.Idle != 0 {
+		t.Errorf("stats after CloseIdleConnections got %+v", got)
+	}
+}
+
+func TestOptionConnectionReuse(t *testing.T) {
+	conns := int32(0)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("goya"))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	opt := NewOption(WithMaxIdleConnsPerHost(10), WithIdleConnTimeout(time.Minute))
+	for i := 0; i < 5; i++ {
+		RequestRaw(http.MethodGet, server.URL, opt).Bytes()
+		// the options without ClientBuildFuncs keep the transport of opt
+		RequestRaw(http.MethodGet, server.URL, opt.with(WithForceHeader("Test-Header", "goya"))).Bytes()
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("connections of the requests sharing the Option got %v but want %v", n, 1)
+	}
+
+	// the default Option and opt both have ClientBuildFuncs, the requests with the same pair share the client
+	SetDefaultOption(NewOption(WithIdleConnTimeout(time.Minute)))
+	defer SetDefaultOption(nil)
+	atomic.StoreInt32(&conns, 0)
+	for i := 0; i < 2; i++ {
+		RequestRaw(http.MethodGet, server.URL, opt).Bytes()
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("connections of the requests with the default Option got %v but want %v", n, 1)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("goya"))
+	}))
+	defer server.Close()
+
+	errs := []error{}
+	RequestRaw(http.MethodGet, server.URL, NewOption(WithResponseHeaderTimeout(10*time.Millisecond), WithError(&errs)))
+	if len(errs) == 0 {
+		t.Errorf("WithResponseHeaderTimeout got no error")
+	}
+
+	// the transport given by WithTransport is cloned before it is configured
+	transport := &http.Transport{}
+	RequestRaw(http.MethodGet, server.URL, NewOption(WithTransport(transport), WithResponseHeaderTimeout(time.Second))).Bytes()
+	if transport.ResponseHeaderTimeout != 0 {
+		t.Errorf("the ResponseHeaderTimeout of the given transport got %v but want %v", transport.ResponseHeaderTimeout, 0)
+	}
+
+	hang := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	errs = []error{}
+	start := time.Now()
+	RequestRaw(http.MethodGet, server.URL, NewOption(WithDialer(hang), WithDialTimeout(10*time.Millisecond), WithError(&errs)))
+	if len(errs) == 0 || time.Since(start) > time.Second {
+		t.Errorf("WithDialTimeout got %v after %v", errs, time.Since(start))
+	}
+}
@@ -0,0 +1,129 @@
+package goya
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter caps the throughput of the bodies using it in bytes per second
+// The same RateLimiter can be shared by many requests to cap their total throughput
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a token bucket of bytesPerSecond, which allows bursts of a tenth of a second
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	rate := float64(bytesPerSecond)
+	burst := max(rate/10, 1)
+	return &RateLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// WithUploadLimit will cap the throughput of the request body, the limiter is created for each request
+func WithUploadLimit(bytesPerSecond int64) OptionFunc {
+	return withBodyLimit(bytesPerSecond, nil, true)
+}
+
+// WithDownloadLimit will cap the throughput of the response body, the limiter is created for each request
+func WithDownloadLimit(bytesPerSecond int64) OptionFunc {
+	return withBodyLimit(bytesPerSecond, nil, false)
+}
+
+// WithUploadLimiter will cap the throughput of the request body with limiter, which can be shared by many requests
+func WithUploadLimiter(limiter *RateLimiter) OptionFunc {
+	return withBodyLimit(0, limiter, true)
+}
+
+// WithDownloadLimiter will cap the throughput of the response body with limiter, which can be shared by many requests
+func WithDownloadLimiter(limiter *RateLimiter) OptionFunc {
+	return withBodyLimit(0, limiter, false)
+}
+
+func withBodyLimit(bytesPerSecond int64, limiter *RateLimiter, upload bool) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		if limiter == nil && bytesPerSecond <= 0 {
+			return func(b *RequestBuider) {
+				b.ErrHappen(fmt.Errorf("the rate limit must be positive but got %d", bytesPerSecond))
+			}, nil, nil, nil
+		}
+		newLimiter := func() *RateLimiter {
+			if limiter != nil {
+				return limiter
+			}
+			return NewRateLimiter(bytesPerSecond)
+		}
+		if upload {
+			return nil, func(req *http.Request) {
+				if req == nil {
+					return
+				}
+				// The body is wrapped when it is sent, and the body sent again by a redirect shares the limiter
+				limiter := newLimiter()
+				withSendBody(func(body io.ReadCloser, req *http.Request) io.ReadCloser {
+					return &limitedReader{ReadCloser: body, ctx: req.Context(), limiter: limiter}
+				})(req)
+			}, nil, nil
+		}
+		return nil, nil, nil, func(errs []error, resp *Response) {
+			raw := resp.RawResponse
+			if raw == nil || raw.Body == nil {
+				return
+			}
+			ctx := context.Background()
+			if raw.Request != nil {
+				ctx = raw.Request.Context()
+			}
+			raw.Body = &limitedReader{ReadCloser: raw.Body, ctx: ctx, limiter: newLimiter()}
+		}
+	}
+}
+
+// wait takes n tokens and waits until the bucket is not in debt
+// The tokens are taken before waiting, so the readers sharing the limiter are served in turn
+func (l *RateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	debt := -l.tokens
+	l.mu.Unlock()
+	if debt <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(debt / l.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedReader reads at most a burst at a time and waits for the limiter after each read
+type limitedReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *RateLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if burst := int(r.limiter.burst); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
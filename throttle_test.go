@@ -0,0 +1,96 @@
+package goya
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithLimit(t *testing.T) {
+	content := bytes.Repeat([]byte("goya"), 5000)
+	received := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bts, _ := io.ReadAll(r.Body)
+		received = len(bts)
+		w.Write(content)
+	})
+
+	// 20000 bytes at 100000 bytes per second, minus the first burst of 10000 bytes
+	start := time.Now()
+	RequestRaw(http.MethodPost, "http://goya.test/post", NewOption(WithHandler(handler), withRawBody(content, "text/plain"), WithUploadLimit(100000)))
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || received != len(content) {
+		t.Errorf("upload got %v bytes in %v", received, elapsed)
+	}
+
+	start = time.Now()
+	bts, err := RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithHandler(handler), WithDownloadLimit(100000))).Bytes()
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || err != nil || len(bts) != len(content) {
+		t.Errorf("download got %v bytes in %v : %v", len(bts), elapsed, err)
+	}
+
+	// The two requests share 100000 bytes per second
+	limiter := NewRateLimiter(100000)
+	download := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(content) })
+	opt := NewOption(WithHandler(download), WithDownloadLimiter(limiter))
+	start = time.Now()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RequestRaw(http.MethodGet, "http://goya.test/get", opt).Bytes()
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 280*time.Millisecond {
+		t.Errorf("shared download got %v bytes in %v", 2*len(content), elapsed)
+	}
+
+	errs := []error{}
+	RequestRaw(http.MethodGet, "http://goya.test/get", NewOption(WithDownloadLimit(0), WithHandler(http.NotFoundHandler()), WithError(&errs)))
+	if want := "the rate limit must be positive but got 0"; len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("WithDownloadLimit(0) got %v but want %v", errs, want)
+	}
+}
+
+func TestWithUploadLimitOrder(t *testing.T) {
+	// random bytes are not made smaller by the compression
+	content := make([]byte, 6000)
+	rand.New(rand.NewSource(1)).Read(content)
+	received := []int{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bts, _ := io.ReadAll(r.Body)
+		received = append(received, len(bts))
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/post", http.StatusTemporaryRedirect)
+		}
+	})
+	body := withRawBody(content, "application/octet-stream")
+	compression := WithRequestCompression(CompressionGzip)
+	limit := WithUploadLimit(20000)
+
+	// 6000 bytes at 20000 bytes per second, minus the first burst of 2000 bytes
+	for name, opt := range map[string]*Option{
+		"limit first":       NewOption(WithHandler(handler), body, limit, compression),
+		"compression first": NewOption(WithHandler(handler), body, compression, limit),
+	} {
+		received = received[:0]
+		start := time.Now()
+		RequestRaw(http.MethodPost, "http://goya.test/post", opt).Bytes()
+		if elapsed := time.Since(start); elapsed < 180*time.Millisecond || len(received) != 1 || received[0] < len(content) {
+			t.Errorf("%v got %v bytes in %v", name, received, elapsed)
+		}
+	}
+
+	// the body sent again by the redirect shares the limiter, 12000 bytes minus the burst
+	received = received[:0]
+	start := time.Now()
+	RequestRaw(http.MethodPost, "http://goya.test/redirect", NewOption(WithHandler(handler), body, limit)).Bytes()
+	if elapsed := time.Since(start); elapsed < 480*time.Millisecond || len(received) != 2 {
+		t.Errorf("redirect got %v bytes in %v", received, elapsed)
+	}
+}
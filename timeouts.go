@@ -0,0 +1,67 @@
+package goya
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithDialTimeout will limit the time to open a connection, including the DNS lookup
+func WithDialTimeout(timeout time.Duration) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			configureDial(client, func(c *dialConfig) {
+				c.timeout = timeout
+			})
+		}, nil
+	}
+}
+
+// WithTLSHandshakeTimeout will limit the time of the TLS handshake
+func WithTLSHandshakeTimeout(timeout time.Duration) OptionFunc {
+	return transportOption(func(t *http.Transport) {
+		t.TLSHandshakeTimeout = timeout
+	})
+}
+
+// WithResponseHeaderTimeout will limit the time to wait for the response headers after the request is sent
+func WithResponseHeaderTimeout(timeout time.Duration) OptionFunc {
+	return transportOption(func(t *http.Transport) {
+		t.ResponseHeaderTimeout = timeout
+	})
+}
+
+// WithIdleConnTimeout will close the connections which have been idle in the pool for timeout
+func WithIdleConnTimeout(timeout time.Duration) OptionFunc {
+	return transportOption(func(t *http.Transport) {
+		t.IdleConnTimeout = timeout
+	})
+}
+
+// WithExpectContinueTimeout will limit the time to wait for 100 Continue if the request has Expect: 100-continue
+func WithExpectContinueTimeout(timeout time.Duration) OptionFunc {
+	return transportOption(func(t *http.Transport) {
+		t.ExpectContinueTimeout = timeout
+	})
+}
+
+// WithMaxIdleConnsPerHost will keep at most n idle connections for each host in the pool
+func WithMaxIdleConnsPerHost(n int) OptionFunc {
+	return transportOption(func(t *http.Transport) {
+		t.MaxIdleConnsPerHost = n
+	})
+}
+
+// WithMaxConnsPerHost will open at most n connections to each host, the requests wait for a connection beyond that
+func WithMaxConnsPerHost(n int) OptionFunc {
+	return transportOption(func(t *http.Transport) {
+		t.MaxConnsPerHost = n
+	})
+}
+
+func transportOption(f func(t *http.Transport)) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			configureTransport(client, f)
+		}, nil
+	}
+}
@@ -0,0 +1,167 @@
+package goya
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings is the duration of each phase of a request
+// The phases of the last hop are kept if the request was redirected
+type Timings struct {
+	// DNS is zero if the host is an IP or the connection was reused
+	DNS time.Duration
+	// Connect is the time to establish the TCP connection, zero if the connection was reused
+	Connect time.Duration
+	// TLSHandshake is zero for http or if the connection was reused
+	TLSHandshake time.Duration
+	// FirstByte is the time from the start of the request to the first byte of the response
+	FirstByte time.Duration
+	// BodyRead is the time from the first byte of the response to the end of the body
+	// It is only known after the body has been read, such as by Bytes() or String()
+	BodyRead time.Duration
+	// Total is the time from the start of the request to the end of the body,
+	// or to the first byte of the response before the body is read
+	Total time.Duration
+	// Reused reports whether the connection was reused from the pool
+	Reused bool
+}
+
+type timingsKey struct{}
+
+// timingsRecorder collects the events of the httptrace.ClientTrace of one request
+type timingsRecorder struct {
+	mu           sync.Mutex
+	timings      Timings
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	firstByte    time.Time
+}
+
+// WithTimings will record the duration of each phase of the request to Response.Timings
+func WithTimings() OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, func(req *http.Request) {
+				if req == nil {
+					return
+				}
+				r := &timingsRecorder{}
+				ctx := context.WithValue(req.Context(), timingsKey{}, r)
+				*req = *req.WithContext(httptrace.WithClientTrace(ctx, r.trace()))
+			}, nil, func(errs []error, resp *Response) {
+				if resp.RawResponse == nil || resp.RawResponse.Request == nil {
+					return
+				}
+				r, ok := resp.RawResponse.Request.Context().Value(timingsKey{}).(*timingsRecorder)
+				if !ok {
+					return
+				}
+				resp.Timings = r.result()
+				resp.RawResponse.Body = &timingsBody{ReadCloser: resp.RawResponse.Body, recorder: r, timings: resp.Timings}
+			}
+	}
+}
+
+func (r *timingsRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if r.start.IsZero() {
+				r.start = time.Now()
+			}
+			// A redirect starts a new hop, only the last one is kept
+			r.timings.DNS, r.timings.Connect, r.timings.TLSHandshake = 0, 0, 0
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.timings.Reused = info.Reused
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.timings.DNS = time.Since(r.dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.connectStart = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.timings.Connect = time.Since(r.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.timings.TLSHandshake = time.Since(r.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if r.start.IsZero() {
+				return
+			}
+			r.firstByte = time.Now()
+			r.timings.FirstByte = r.firstByte.Sub(r.start)
+			r.timings.Total = r.timings.FirstByte
+		},
+	}
+}
+
+func (r *timingsRecorder) result() *Timings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timings := r.timings
+	return &timings
+}
+
+// done is called when the body has been read or closed
+func (r *timingsRecorder) done(timings *Timings) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.firstByte.IsZero() {
+		return
+	}
+	timings.BodyRead = time.Since(r.firstByte)
+	timings.Total = time.Since(r.start)
+}
+
+// timingsBody records the end of the body into timings
+type timingsBody struct {
+	io.ReadCloser
+	recorder *timingsRecorder
+	timings  *Timings
+	once     sync.Once
+}
+
+func (b *timingsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(func() { b.recorder.done(b.timings) })
+	}
+	return n, err
+}
+
+func (b *timingsBody) Close() error {
+	b.once.Do(func() { b.recorder.done(b.timings) })
+	return b.ReadCloser.Close()
+}
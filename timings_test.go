@@ -0,0 +1,47 @@
+package goya
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithTimings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("second"))
+	}))
+	defer server.Close()
+	opt := NewOption(WithTransport(server.Client().Transport), WithTimings())
+
+	resp := RequestRaw(http.MethodGet, server.URL, opt)
+	if resp.Timings == nil {
+		t.Fatal("Timings got nil")
+	}
+	if resp.Timings.Connect <= 0 || resp.Timings.FirstByte <= 0 || resp.Timings.Reused {
+		t.Errorf("Timings got %+v", resp.Timings)
+	}
+	if resp.Timings.BodyRead != 0 {
+		t.Errorf("BodyRead got %v before the body is read", resp.Timings.BodyRead)
+	}
+	if body, _ := resp.String(); body != "firstsecond" {
+		t.Errorf("body got %v but want %v", body, "firstsecond")
+	}
+	if resp.Timings.BodyRead < 10*time.Millisecond || resp.Timings.Total < resp.Timings.FirstByte+resp.Timings.BodyRead {
+		t.Errorf("Timings after reading the body got %+v", resp.Timings)
+	}
+
+	reused := RequestRaw(http.MethodGet, server.URL, opt)
+	reused.Bytes()
+	if !reused.Timings.Reused || reused.Timings.Connect != 0 {
+		t.Errorf("Timings of the reused connection got %+v", reused.Timings)
+	}
+
+	resp = RequestRaw(http.MethodGet, server.URL, NewOption(WithTransport(server.Client().Transport)))
+	if resp.Timings != nil {
+		t.Error("Timings should be nil without WithTimings")
+	}
+}
@@ -0,0 +1,152 @@
+package goya
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrPinMismatch fails the handshake if no certificate of the server matches the pins of WithPinnedPublicKeys
+var ErrPinMismatch = errors.New("goya: no certificate matches the pinned public keys")
+
+// WithTLSConfig will set a copy of config to the transport
+// It replaces the config set by the TLS options before it, and the TLS options after it change the copy
+func WithTLSConfig(config *tls.Config) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			configureTransport(client, func(t *http.Transport) {
+				t.TLSClientConfig = config.Clone()
+			})
+		}, nil
+	}
+}
+
+// WithRootCAs will verify the server certificate with the CAs of the PEM files instead of the system ones
+func WithRootCAs(pemFiles ...string) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		pool := x509.NewCertPool()
+		for _, name := range pemFiles {
+			bts, err := os.ReadFile(name)
+			if err == nil && !pool.AppendCertsFromPEM(bts) {
+				err = fmt.Errorf("no certificate is found")
+			}
+			if err != nil {
+				return func(b *RequestBuider) { b.ErrHappen(fmt.Errorf("WithRootCAs %v : %w", name, err)) }, nil, nil, nil
+			}
+		}
+		return nil, nil, func(client *http.Client) {
+			configureTLS(client, func(c *tls.Config) {
+				c.RootCAs = pool
+			})
+		}, nil
+	}
+}
+
+// WithClientCertificate will send the certificate of the PEM files for mutual TLS
+// The files are loaded again for a new connection if they have been modified, so the rotated certificate is used
+func WithClientCertificate(certFile, keyFile string) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		loader := &certificateLoader{certFile: certFile, keyFile: keyFile}
+		if _, err := loader.certificate(); err != nil {
+			return func(b *RequestBuider) { b.ErrHappen(fmt.Errorf("WithClientCertificate : %w", err)) }, nil, nil, nil
+		}
+		return nil, nil, func(client *http.Client) {
+			configureTLS(client, func(c *tls.Config) {
+				c.Certificates = nil
+				c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+					return loader.certificate()
+				}
+			})
+		}, nil
+	}
+}
+
+// WithMinTLSVersion will refuse the servers which do not support version, such as tls.VersionTLS13
+func WithMinTLSVersion(version uint16) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		return nil, nil, func(client *http.Client) {
+			configureTLS(client, func(c *tls.Config) {
+				c.MinVersion = version
+			})
+		}, nil
+	}
+}
+
+// WithPinnedPublicKeys will fail the handshake with ErrPinMismatch unless a certificate of the server chain
+// has one of the public keys, each pin is the base64 encoded SHA-256 of a SubjectPublicKeyInfo like HPKP
+// The pins are checked in addition to the usual verification of the chain
+func WithPinnedPublicKeys(pins ...string) OptionFunc {
+	return func() (BeforeBuildFunc, AfterBuildFunc, ClientBuildFunc, ClientDoneFunc) {
+		pinned := map[string]bool{}
+		for _, pin := range pins {
+			pinned[pin] = true
+		}
+		return nil, nil, func(client *http.Client) {
+			configureTLS(client, func(c *tls.Config) {
+				verify := c.VerifyConnection
+				c.VerifyConnection = func(state tls.ConnectionState) error {
+					if verify != nil {
+						if err := verify(state); err != nil {
+							return err
+						}
+					}
+					for _, cert := range state.PeerCertificates {
+						if pinned[PublicKeyPin(cert)] {
+							return nil
+						}
+					}
+					return ErrPinMismatch
+				}
+			})
+		}, nil
+	}
+}
+
+// PublicKeyPin returns the pin of cert for WithPinnedPublicKeys
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// certificateLoader caches the client certificate until its files are modified
+type certificateLoader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime [2]time.Time
+}
+
+func (l *certificateLoader) certificate() (*tls.Certificate, error) {
+	modTime := [2]time.Time{}
+	for i, name := range []string{l.certFile, l.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		modTime[i] = info.ModTime()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cert != nil && modTime == l.modTime {
+		return l.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		// Keep the last certificate while the files are being rotated
+		if l.cert != nil {
+			return l.cert, nil
+		}
+		return nil, err
+	}
+	l.cert, l.modTime = &cert, modTime
+	return l.cert, nil
+}
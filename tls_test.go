@@ -0,0 +1,113 @@
+package goya
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed client certificate of commonName to certFile and keyFile
+func writeCertificate(t *testing.T, commonName, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+}
+
+func TestWithRootCAs(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("goya"))
+	}))
+	// The failed handshakes are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+
+	errs := []error{}
+	RequestRaw(http.MethodGet, server.URL, NewOption(WithError(&errs)))
+	if len(errs) == 0 {
+		t.Errorf("the unknown CA got no error")
+	}
+
+	got, _ := RequestRaw(http.MethodGet, server.URL, NewOption(WithRootCAs(ca), WithMinTLSVersion(tls.VersionTLS12))).String()
+	if got != "goya" {
+		t.Errorf("body got %v but want %v", got, "goya")
+	}
+
+	pin := PublicKeyPin(server.Certificate())
+	got, _ = RequestRaw(http.MethodGet, server.URL, NewOption(WithRootCAs(ca), WithPinnedPublicKeys("other", pin))).String()
+	if got != "goya" {
+		t.Errorf("pinned body got %v but want %v", got, "goya")
+	}
+	errs = []error{}
+	RequestRaw(http.MethodGet, server.URL, NewOption(WithRootCAs(ca), WithPinnedPublicKeys("other"), WithError(&errs)))
+	if len(errs) == 0 || !errors.Is(errs[0], ErrPinMismatch) {
+		t.Errorf("errs got %v but want %v", errs, ErrPinMismatch)
+	}
+
+	errs = []error{}
+	RequestRaw(http.MethodGet, server.URL, NewOption(WithRootCAs(filepath.Join(t.TempDir(), "missing.pem")), WithError(&errs)))
+	if len(errs) == 0 {
+		t.Errorf("the missing CA file got no error")
+	}
+}
+
+func TestWithClientCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writeCertificate(t, "first", certFile, keyFile)
+	opt := NewOption(WithInsecureSkipVerify(), WithClientCertificate(certFile, keyFile))
+	if got, _ := RequestRaw(http.MethodGet, server.URL, opt).String(); got != "first" {
+		t.Errorf("common name got %v but want %v", got, "first")
+	}
+
+	writeCertificate(t, "second", certFile, keyFile)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	// the certificate is loaded again for a new connection, the requests with opt share the connections
+	server.CloseClientConnections()
+	if got, _ := RequestRaw(http.MethodGet, server.URL, opt).String(); got != "second" {
+		t.Errorf("common name after the rotation got %v but want %v", got, "second")
+	}
+}
@@ -0,0 +1,36 @@
+package goya
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// configureTransport calls f with a clone of the *http.Transport of the client, or of http.DefaultTransport if it is nil
+// The transport given by WithTransport may be used by other goroutines, so it is not changed,
+// and f is not called if the client uses another RoundTripper
+// The client is built once per Option, so the clone and its connection pool are shared by the requests using the Option
+func configureTransport(client *http.Client, f func(t *http.Transport)) {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return
+	}
+	f(transport)
+	client.Transport = transport
+}
+
+// configureTLS calls f with a copy of the tls.Config of the client transport, which is created if it is nil
+func configureTLS(client *http.Client, f func(c *tls.Config)) {
+	configureTransport(client, func(t *http.Transport) {
+		config := t.TLSClientConfig.Clone()
+		if config == nil {
+			config = &tls.Config{}
+		}
+		f(config)
+		t.TLSClientConfig = config
+	})
+}
@@ -1,10 +1,12 @@
 package goya
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -130,3 +132,37 @@ func compareResp(first *BasicGetResponse, second *BasicGetResponse) bool {
 
 	return reflect.DeepEqual(firstArgs, secondArgs)
 }
+
+// lookupJSONPath returns the raw JSON found at the dotted path in body, such as "meta.next_cursor" or "data.0.id"
+// An empty path returns the whole body
+func lookupJSONPath(body []byte, path string) (json.RawMessage, error) {
+	current := json.RawMessage(body)
+	if path == "" {
+		return current, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		trimmed := bytes.TrimSpace(current)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			arr := []json.RawMessage{}
+			if err := json.Unmarshal(trimmed, &arr); err != nil {
+				return nil, err
+			}
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(arr) {
+				return nil, fmt.Errorf("JSON path %q : index %q is out of range", path, key)
+			}
+			current = arr[idx]
+			continue
+		}
+		obj := map[string]json.RawMessage{}
+		if err := json.Unmarshal(trimmed, &obj); err != nil {
+			return nil, fmt.Errorf("JSON path %q : %w", path, err)
+		}
+		val, ok := obj[key]
+		if !ok {
+			return nil, nil
+		}
+		current = val
+	}
+	return current, nil
+}